@@ -2,39 +2,224 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/leow93/miffed-api/internal/config"
 	"github.com/leow93/miffed-api/internal/httpadapter"
 	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/metrics"
 	"github.com/leow93/miffed-api/internal/pubsub"
+	"github.com/leow93/miffed-api/internal/recording"
 	"github.com/rs/cors"
 )
 
-func callLift(svc *lift.LiftService, id lift.LiftId, floor int) {
-	ctx := context.TODO()
-	err := svc.CallLift(ctx, id, floor)
+func authOptions(apiKeys, jwtSecret string) ([]httpadapter.AuthOption, error) {
+	var opts []httpadapter.AuthOption
+	for _, pair := range strings.Split(apiKeys, ",") {
+		if pair == "" {
+			continue
+		}
+		key, r, _ := strings.Cut(pair, "=")
+		role, err := httpadapter.ParseRole(r)
+		if err != nil {
+			return nil, fmt.Errorf("role %q for api key: %w", r, err)
+		}
+		opts = append(opts, httpadapter.WithAPIKey(key, role))
+	}
+	if jwtSecret != "" {
+		opts = append(opts, httpadapter.WithJWTSecret([]byte(jwtSecret)))
+	}
+	return opts, nil
+}
+
+// loadConfig loads the config file at path, if any, with the environment's
+// overrides and then those of any flags set on the command line.
+func loadConfig(path string, floors, lobby *int, parking *string, parkAfter *time.Duration, maxStops *int) (config.Config, error) {
+	cfg, err := config.Load(path, os.Getenv)
 	if err != nil {
-		panic(err)
+		return cfg, err
 	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "floors":
+			cfg.Building.Floors = *floors
+		case "lobby":
+			cfg.Building.Lobby = *lobby
+		case "parking":
+			cfg.Building.Parking.Policy = lift.ParkingPolicy(*parking)
+		case "park-after":
+			cfg.Building.Parking.IdleTimeout.Duration = *parkAfter
+		case "max-stops":
+			cfg.Building.MaxPendingStops = *maxStops
+		}
+	})
+	return cfg, cfg.Validate()
 }
 
-const address = ":8080"
+// logChanges logs how reconciling the building changed its lifts, and
+// records each change.
+func logChanges(rec *recording.Recorder, b config.Building, changes []config.Change) {
+	for _, c := range changes {
+		log.Printf("lift %s (%s) %s", c.Lift, c.LiftId, c.Type)
+		if c.Type == config.ChangeRetired {
+			rec.RecordCommand(recording.Command{Type: recording.CommandRetireLift, LiftId: c.LiftId})
+			continue
+		}
+		l, ok := b.Lift(c.Lift)
+		if !ok {
+			continue
+		}
+		switch c.Type {
+		case config.ChangeAdded:
+			rec.RecordCommand(recording.Command{
+				Type:         recording.CommandAddLift,
+				LiftId:       c.LiftId,
+				Floor:        l.Floor,
+				FloorDelayMs: l.FloorDelayMs,
+				DoorDwellMs:  l.DoorDwellMs,
+				Scheduler:    l.Scheduler,
+				ServedFloors: l.LiftConfig().ServedFloors,
+				Decks:        l.Decks,
+				Shaft:        l.Shaft,
+			})
+		case config.ChangeUpdated:
+			rec.RecordCommand(recording.Command{
+				Type:         recording.CommandUpdateLift,
+				LiftId:       c.LiftId,
+				FloorDelayMs: l.FloorDelayMs,
+				DoorDwellMs:  l.DoorDwellMs,
+				Scheduler:    l.Scheduler,
+			})
+		}
+	}
+}
+
+func newCors(origins []string) *cors.Cors {
+	if len(origins) == 1 && origins[0] == "*" {
+		return cors.AllowAll()
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins: origins,
+		AllowedMethods: []string{http.MethodHead, http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders: []string{"*"},
+	})
+}
 
 func main() {
+	configPath := flag.String("config", os.Getenv("MIFFED_CONFIG"), "JSON file describing the server and its building; flags given override it")
+	watch := flag.Duration("watch", 5*time.Second, "how often to check the config file for changes to its lifts, or 0 to only reload on POST /admin/reload")
+	record := flag.String("record", "", "file to record lift commands and events to, for replaying later")
+	parking := flag.String("parking", "none", "where idle lifts park: none, lobby, zones or demand")
+	parkAfter := flag.Duration("park-after", 30*time.Second, "time a lift must be idle before it parks")
+	lobby := flag.Int("lobby", 0, "lobby floor, used when parking")
+	floors := flag.Int("floors", 0, "number of floors in the building, numbered up from its lowest floor, used to validate requests and when parking in zones")
+	apiKeys := flag.String("api-keys", os.Getenv("MIFFED_API_KEYS"), "comma separated key=role pairs of static API keys; roles are viewer, passenger and operator")
+	jwtSecret := flag.String("jwt-secret", os.Getenv("MIFFED_JWT_SECRET"), "secret JWTs are signed with using HS256")
+	rate := flag.Float64("rate", 10, "requests per second each client may make, or 0 for no limit")
+	burst := flag.Int("burst", 20, "requests a client may make at once before being rate limited")
+	maxStops := flag.Int("max-stops", 0, "stops each lift may have pending before refusing calls to new floors, or 0 for no limit")
+	idempotencyTTL := flag.Duration("idempotency-ttl", 24*time.Hour, "how long responses are kept for replaying to requests retried with the same Idempotency-Key")
+	flag.Parse()
+
+	auth, err := authOptions(*apiKeys, *jwtSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := loadConfig(*configPath, floors, lobby, parking, parkAfter, maxStops)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
-	ps := pubsub.NewMemoryPubSub()
-	svc := lift.NewLiftService(ctx, ps)
+	reg := metrics.NewRegistry()
+	ps := pubsub.NewMemoryPubSub(pubsub.WithMetrics(reg))
+	svc := lift.NewLiftService(ctx, ps,
+		lift.WithMetrics(reg),
+		lift.WithLowestFloor(cfg.Building.LowestFloor),
+		lift.WithParking(cfg.Building.ParkingConfig()),
+		lift.WithMaxPendingStops(cfg.Building.MaxPendingStops),
+		lift.WithAccessRules(cfg.Building.AccessRules()...),
+	)
 	subs := lift.NewSubscriptionManager(ctx, ps)
 
+	var rec *recording.Recorder
+	if *record != "" {
+		f, err := os.Create(*record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		rec = recording.NewRecorder(f)
+		if err := rec.RecordEvents(ctx, subs); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	reconciler := config.NewReconciler(svc)
+	changes, err := reconciler.Apply(ctx, cfg.Building)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logChanges(rec, cfg.Building, changes)
+	err = reconciler.RetryWhenRetired(ctx, subs, func(b config.Building, changes []config.Change, err error) {
+		logChanges(rec, b, changes)
+		if err != nil {
+			log.Print(err)
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	reload := func(ctx context.Context) ([]config.Change, error) {
+		cfg, err := loadConfig(*configPath, floors, lobby, parking, parkAfter, maxStops)
+		if err != nil {
+			return nil, err
+		}
+		changes, err := reconciler.Apply(ctx, cfg.Building)
+		logChanges(rec, cfg.Building, changes)
+		return changes, err
+	}
+
 	mux := http.NewServeMux()
-	mux = httpadapter.NewController(mux, svc)
-	mux = httpadapter.NewSocket(mux, subs)
+	controllerOpts := []httpadapter.Option{httpadapter.WithRecorder(rec), httpadapter.WithIdempotencyTTL(*idempotencyTTL)}
+	if *configPath != "" {
+		controllerOpts = append(controllerOpts, httpadapter.WithReload(reload))
+		if *watch > 0 {
+			go config.Watch(ctx, *configPath, *watch, func() {
+				if _, err := reload(ctx); err != nil {
+					log.Printf("reloading %s: %v", *configPath, err)
+				}
+			})
+		}
+	}
+	if highest, ok := cfg.Building.HighestFloor(); ok {
+		controllerOpts = append(controllerOpts, httpadapter.WithFloors(cfg.Building.LowestFloor, highest))
+	}
+	mux = httpadapter.NewController(mux, svc, controllerOpts...)
+	mux = httpadapter.NewSocket(mux, subs, httpadapter.WithOrigins(cfg.CORS.Origins...))
+	mux = httpadapter.NewMetrics(mux, reg)
+	mux = httpadapter.NewOpenAPI(mux)
 
-	server := cors.AllowAll().Handler(mux)
+	var handler http.Handler
+	limit := httpadapter.RateLimit{Rate: *rate, Burst: *burst}
+	if len(auth) > 0 {
+		handler = httpadapter.NewAuth(mux, append(auth, httpadapter.WithRateLimit(limit))...)
+	} else {
+		log.Print("no API keys or JWT secret given, so requests are not authenticated")
+		handler = httpadapter.NewRateLimit(mux, limit)
+	}
+	server := newCors(cfg.CORS.Origins).Handler(handler)
 
-	if err := http.ListenAndServe(address, server); err != nil {
+	log.Printf("listening on %s", cfg.Listen)
+	if err := http.ListenAndServe(cfg.Listen, server); err != nil {
 		cancel()
 		log.Fatal(err)
 	}
@@ -0,0 +1,108 @@
+// Command replay plays a session recorded with `api -record` into a fresh
+// LiftService, optionally serving it so the web app can watch.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/httpadapter"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+	"github.com/leow93/miffed-api/internal/recording"
+	"github.com/rs/cors"
+)
+
+func main() {
+	var (
+		file  = flag.String("file", "", "recording to replay")
+		speed = flag.Float64("speed", 1, "how many times faster than real time to replay")
+		addr  = flag.String("addr", "", "address to serve the replayed lifts on, e.g. :8080")
+		out   = flag.String("out", "", "file to record the replayed session to, for comparing with the original")
+	)
+	flag.Parse()
+	if *file == "" || *speed <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	entries, err := recording.Read(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := clock.NewScaled(*speed)
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps, lift.WithClock(c))
+	subs := lift.NewSubscriptionManager(ctx, ps)
+
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		if err := recording.NewRecorder(f).RecordEvents(ctx, subs); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *addr != "" {
+		mux := http.NewServeMux()
+		mux = httpadapter.NewController(mux, svc)
+		mux = httpadapter.NewSocket(mux, subs, httpadapter.WithOrigins("*"))
+		go func() {
+			log.Fatal(http.ListenAndServe(*addr, cors.AllowAll().Handler(mux)))
+		}()
+	}
+
+	outcomes, err := recording.Replay(ctx, entries, svc, c)
+	if err != nil {
+		log.Printf("stopped replaying: %v", err)
+	}
+	// Let the lifts finish whatever the recording saw them do after the last command.
+	if len(entries) > 0 {
+		c.Sleep(entries[len(entries)-1].At.Sub(lastCommandAt(entries)))
+	}
+	// Give the final events time to reach the recorder.
+	time.Sleep(100 * time.Millisecond)
+	report(outcomes)
+
+	if *addr != "" {
+		select {}
+	}
+}
+
+// report logs how many commands were replayed and each that failed, where
+// the replay diverged from the recorded session.
+func report(outcomes []recording.Outcome) {
+	diverged := 0
+	for _, o := range outcomes {
+		if o.Diverged() {
+			diverged++
+			log.Printf("%s recorded at %s succeeded, but failed on replay: %v", o.Command.Type, o.At.Format(time.RFC3339Nano), o.Err)
+		}
+	}
+	log.Printf("replayed %d commands, %d of which diverged from the recording", len(outcomes), diverged)
+}
+
+func lastCommandAt(entries []recording.Entry) time.Time {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Command != nil {
+			return entries[i].At
+		}
+	}
+	return entries[len(entries)-1].At
+}
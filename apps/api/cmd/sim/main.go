@@ -0,0 +1,175 @@
+// Command sim runs simulated passenger traffic through a building once per
+// scheduler and compares how each performed.
+//
+// The seed fixes which passengers arrive and when, but not the results: the
+// virtual clock moves on once the lifts have been quiet for a moment of real
+// time, so a busy machine can change how calls interleave and the figures can
+// differ a little between runs. Compare schedulers within a run, or average
+// several.
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/simulation"
+)
+
+type row struct {
+	Scheduler         lift.Scheduler `json:"scheduler"`
+	Passengers        int            `json:"passengers"`
+	Failed            int            `json:"failed"`
+	AverageWaitSec    float64        `json:"average_wait_seconds"`
+	AverageJourneySec float64        `json:"average_journey_seconds"`
+	FloorsTravelled   int            `json:"floors_travelled"`
+	Stops             int            `json:"stops"`
+	EnergyWh          float64        `json:"energy_wh"`
+}
+
+func newRow(r simulation.Result) row {
+	return row{
+		Scheduler:         r.Scheduler,
+		Passengers:        r.Passengers,
+		Failed:            r.Failed,
+		AverageWaitSec:    r.AverageWait.Seconds(),
+		AverageJourneySec: r.AverageJourney.Seconds(),
+		FloorsTravelled:   r.FloorsTravelled,
+		Stops:             r.Stops,
+		EnergyWh:          r.EnergyWh,
+	}
+}
+
+// loadEnergyModel assumes 75kg passengers, a counterweight balancing a car
+// 40% full of an 8 person load, 3.5m floors and a motor recovering a third
+// of the energy the imbalance gives back.
+var loadEnergyModel = lift.EnergyModel{
+	FloorWh:      lift.DefaultEnergyModel.FloorWh,
+	StopWh:       lift.DefaultEnergyModel.StopWh,
+	PassengerKg:  75,
+	BalanceKg:    240,
+	WhPerKgFloor: 0.0095,
+	Regeneration: 0.33,
+}
+
+func main() {
+	var (
+		lifts      = flag.Int("lifts", 4, "number of lifts")
+		floors     = flag.Int("floors", 20, "number of floors")
+		lobby      = flag.Int("lobby", 0, "lobby floor")
+		pattern    = flag.String("pattern", string(simulation.PatternUpPeak), "traffic pattern: up_peak, down_peak, lunch, inter_floor or uniform")
+		rate       = flag.Float64("rate", 10, "mean passenger arrivals per minute")
+		seed       = flag.Int64("seed", 1, "random seed for passenger arrivals")
+		duration   = flag.Duration("duration", time.Hour, "simulated time passengers keep arriving for")
+		floorDelay = flag.Duration("floor-delay", 2*time.Second, "time taken to travel one floor")
+		schedulers = flag.String("schedulers", "fifo,nearest,scan", "comma separated schedulers to compare")
+		format     = flag.String("format", "table", "output format: table, json or csv")
+		out        = flag.String("out", "", "file to write results to, defaults to stdout")
+		load       = flag.Bool("load", false, "account for passenger load and counterweight regeneration in energy use")
+		parking    = flag.String("parking", "none", "where idle lifts park: none, lobby, zones or demand")
+		parkAfter  = flag.Duration("park-after", 30*time.Second, "time a lift must be idle before it parks")
+		settle     = flag.Duration("settle", 0, "real time to let lifts settle between simulated events; longer is slower but varies less between runs (default 500µs)")
+		timeout    = flag.Duration("timeout", 10*time.Minute, "real time limit for the whole comparison")
+	)
+	flag.Parse()
+
+	cfg := simulation.BenchmarkConfig{
+		Traffic: simulation.TrafficConfig{
+			Building:          simulation.Building{Floors: *floors, Lobby: *lobby},
+			Pattern:           simulation.Pattern(*pattern),
+			ArrivalsPerMinute: *rate,
+			Seed:              *seed,
+		},
+		Lifts:      *lifts,
+		FloorDelay: *floorDelay,
+		Duration:   *duration,
+		Settle:     *settle,
+	}
+	if *load {
+		cfg.Energy = &loadEnergyModel
+	}
+	policy, err := lift.ParseParkingPolicy(*parking)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Parking = lift.ParkingConfig{Policy: policy, IdleTimeout: *parkAfter}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var rows []row
+	for _, name := range strings.Split(*schedulers, ",") {
+		result, err := simulation.Benchmark(ctx, cfg, lift.Scheduler(strings.TrimSpace(name)))
+		if err != nil {
+			log.Fatalf("running %s: %v", name, err)
+		}
+		rows = append(rows, newRow(result))
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, *format, rows); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func write(w io.Writer, format string, rows []row) error {
+	switch format {
+	case "table":
+		return writeTable(w, rows)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "csv":
+		return writeCSV(w, rows)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+func writeTable(w io.Writer, rows []row) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "scheduler\tpassengers\tfailed\tavg wait (s)\tavg journey (s)\tfloors\tstops\tenergy (Wh)\t")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.1f\t%d\t%d\t%.0f\t\n",
+			r.Scheduler, r.Passengers, r.Failed, r.AverageWaitSec, r.AverageJourneySec, r.FloorsTravelled, r.Stops, r.EnergyWh)
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, rows []row) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"scheduler", "passengers", "failed", "average_wait_seconds", "average_journey_seconds", "floors_travelled", "stops", "energy_wh"})
+	for _, r := range rows {
+		cw.Write([]string{
+			string(r.Scheduler),
+			strconv.Itoa(r.Passengers),
+			strconv.Itoa(r.Failed),
+			strconv.FormatFloat(r.AverageWaitSec, 'f', 3, 64),
+			strconv.FormatFloat(r.AverageJourneySec, 'f', 3, 64),
+			strconv.Itoa(r.FloorsTravelled),
+			strconv.Itoa(r.Stops),
+			strconv.FormatFloat(r.EnergyWh, 'f', 1, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,37 @@
+package clock
+
+import "time"
+
+// Scaled is a wall clock that runs factor times faster than real time.
+type Scaled struct {
+	start  time.Time
+	factor float64
+}
+
+func NewScaled(factor float64) *Scaled {
+	return &Scaled{start: time.Now(), factor: factor}
+}
+
+func (s *Scaled) Now() time.Time {
+	return s.start.Add(s.scale(time.Since(s.start)))
+}
+
+func (s *Scaled) Sleep(d time.Duration) {
+	time.Sleep(s.unscale(d))
+}
+
+func (s *Scaled) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	time.AfterFunc(s.unscale(d), func() {
+		ch <- s.Now()
+	})
+	return ch
+}
+
+func (s *Scaled) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * s.factor)
+}
+
+func (s *Scaled) unscale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / s.factor)
+}
@@ -0,0 +1,24 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScaled(t *testing.T) {
+	t.Run("runs faster than real time", func(t *testing.T) {
+		s := NewScaled(100)
+		started := time.Now()
+		from := s.Now()
+
+		s.Sleep(time.Second)
+		at := <-s.After(time.Second)
+
+		if elapsed := time.Since(started); elapsed > time.Second {
+			t.Errorf("expected two scaled seconds to take well under a second, took %s", elapsed)
+		}
+		if got := at.Sub(from); got < 2*time.Second {
+			t.Errorf("expected at least 2s of scaled time to pass, got %s", got)
+		}
+	})
+}
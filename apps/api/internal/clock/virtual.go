@@ -0,0 +1,178 @@
+package clock
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// Virtual is a clock that only moves when told to. Sleepers and After channels
+// fire as Advance passes their deadline, so hours of lift movement can be
+// simulated in moments.
+type Virtual struct {
+	now      time.Time
+	timers   timerHeap
+	seq      uint64
+	activity uint64 // bumped whenever the clock is used, to detect when goroutines have settled
+	mx       sync.Mutex
+}
+
+func NewVirtual(start time.Time) *Virtual {
+	return &Virtual{now: start}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.activity++
+	return v.now
+}
+
+func (v *Virtual) Sleep(d time.Duration) {
+	<-v.After(d)
+}
+
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.activity++
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- v.now
+		return ch
+	}
+	v.seq++
+	heap.Push(&v.timers, &timer{at: v.now.Add(d), seq: v.seq, ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing every timer due on the way in
+// deadline order.
+func (v *Virtual) Advance(d time.Duration) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	target := v.now.Add(d)
+	for len(v.timers) > 0 && !v.timers[0].at.After(target) {
+		v.fire()
+	}
+	v.now = target
+}
+
+// AdvanceToNext moves the clock to the earliest pending timer and fires every
+// timer due at that instant. It returns false if nothing is waiting.
+func (v *Virtual) AdvanceToNext() bool {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if len(v.timers) == 0 {
+		return false
+	}
+	at := v.timers[0].at
+	for len(v.timers) > 0 && v.timers[0].at.Equal(at) {
+		v.fire()
+	}
+	return true
+}
+
+// fire must be called with v.mx held.
+func (v *Virtual) fire() {
+	t := heap.Pop(&v.timers).(*timer)
+	v.now = t.at
+	v.activity++
+	t.ch <- t.at
+}
+
+// Next returns when the earliest pending timer is due, if there is one.
+func (v *Virtual) Next() (time.Time, bool) {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	if len(v.timers) == 0 {
+		return time.Time{}, false
+	}
+	return v.timers[0].at, true
+}
+
+// RunUntil advances the clock timer by timer up to target, letting goroutines
+// settle before each step as Drive does. It returns false if ctx is done first.
+func (v *Virtual) RunUntil(ctx context.Context, target time.Time, quiet time.Duration) bool {
+	for {
+		if !v.settle(ctx, quiet) {
+			return false
+		}
+		next, ok := v.Next()
+		if !ok || next.After(target) {
+			v.Advance(target.Sub(v.Now()))
+			return v.settle(ctx, quiet)
+		}
+		v.AdvanceToNext()
+	}
+}
+
+// Pending is the number of timers waiting to fire.
+func (v *Virtual) Pending() int {
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	return len(v.timers)
+}
+
+// Drive advances the clock from timer to timer until ctx is done. Before each
+// step it waits for the clock to go unused for quiet, which gives goroutines
+// woken by the previous step a chance to run and schedule their next timer.
+// That is a guess rather than a guarantee: a goroutine that takes longer than
+// quiet to get there finds time has moved on, so runs aren't reproducible.
+func (v *Virtual) Drive(ctx context.Context, quiet time.Duration) {
+	for {
+		if !v.settle(ctx, quiet) {
+			return
+		}
+		v.AdvanceToNext()
+	}
+}
+
+func (v *Virtual) settle(ctx context.Context, quiet time.Duration) bool {
+	for {
+		v.mx.Lock()
+		before := v.activity
+		v.mx.Unlock()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(quiet):
+		}
+		v.mx.Lock()
+		after := v.activity
+		v.mx.Unlock()
+		if before == after {
+			return true
+		}
+	}
+}
+
+type timer struct {
+	at  time.Time
+	seq uint64
+	ch  chan time.Time
+}
+
+// timerHeap orders timers by deadline, then by when they were scheduled.
+type timerHeap []*timer
+
+func (h timerHeap) Len() int { return len(h) }
+
+func (h timerHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h timerHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *timerHeap) Push(x any) { *h = append(*h, x.(*timer)) }
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
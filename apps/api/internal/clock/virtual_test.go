@@ -0,0 +1,129 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVirtual(t *testing.T) {
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("time only moves when advanced", func(t *testing.T) {
+		v := NewVirtual(start)
+		if !v.Now().Equal(start) {
+			t.Fatalf("expected %s, got %s", start, v.Now())
+		}
+		v.Advance(time.Minute)
+		if want := start.Add(time.Minute); !v.Now().Equal(want) {
+			t.Fatalf("expected %s, got %s", want, v.Now())
+		}
+	})
+
+	t.Run("timers fire in deadline order", func(t *testing.T) {
+		v := NewVirtual(start)
+		late := v.After(2 * time.Second)
+		early := v.After(time.Second)
+		if v.Pending() != 2 {
+			t.Fatalf("expected 2 pending timers, got %d", v.Pending())
+		}
+
+		if !v.AdvanceToNext() {
+			t.Fatal("expected a timer to fire")
+		}
+		select {
+		case at := <-early:
+			if want := start.Add(time.Second); !at.Equal(want) {
+				t.Errorf("expected %s, got %s", want, at)
+			}
+		default:
+			t.Fatal("expected the earlier timer to fire")
+		}
+		select {
+		case <-late:
+			t.Fatal("expected the later timer not to have fired")
+		default:
+		}
+
+		v.Advance(time.Hour)
+		select {
+		case <-late:
+		default:
+			t.Fatal("expected the later timer to fire")
+		}
+		if v.AdvanceToNext() {
+			t.Error("expected no timers left")
+		}
+	})
+
+	t.Run("non-positive durations fire immediately", func(t *testing.T) {
+		v := NewVirtual(start)
+		select {
+		case <-v.After(0):
+		default:
+			t.Fatal("expected the timer to have fired")
+		}
+		v.Sleep(-time.Second)
+	})
+
+	t.Run("driving wakes sleepers in turn", func(t *testing.T) {
+		v := NewVirtual(start)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		wg := sync.WaitGroup{}
+		var mx sync.Mutex
+		var woke []time.Time
+		for i := 1; i <= 3; i++ {
+			wg.Add(1)
+			go func(d time.Duration) {
+				defer wg.Done()
+				v.Sleep(d)
+				mx.Lock()
+				woke = append(woke, v.Now())
+				mx.Unlock()
+			}(time.Duration(i) * time.Hour)
+		}
+		for v.Pending() < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		go v.Drive(ctx, time.Millisecond)
+		wg.Wait()
+
+		if len(woke) != 3 {
+			t.Fatalf("expected 3 sleepers to wake, got %d", len(woke))
+		}
+		if want := start.Add(3 * time.Hour); !v.Now().Equal(want) {
+			t.Errorf("expected %s, got %s", want, v.Now())
+		}
+	})
+
+	t.Run("running until a time wakes sleepers due by then", func(t *testing.T) {
+		v := NewVirtual(start)
+		ctx := context.Background()
+		woke := make(chan time.Time, 2)
+		go func() {
+			v.Sleep(time.Second)
+			woke <- v.Now()
+			v.Sleep(time.Minute)
+			woke <- v.Now()
+		}()
+		for v.Pending() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if !v.RunUntil(ctx, start.Add(30*time.Second), time.Millisecond) {
+			t.Fatal("expected to run until the target")
+		}
+		if want := start.Add(time.Second); !(<-woke).Equal(want) {
+			t.Errorf("expected the first sleeper to wake at %s", want)
+		}
+		if want := start.Add(30 * time.Second); !v.Now().Equal(want) {
+			t.Errorf("expected %s, got %s", want, v.Now())
+		}
+		if next, ok := v.Next(); !ok || !next.Equal(start.Add(61*time.Second)) {
+			t.Errorf("expected the second sleeper to be due at 61s, got %s", next)
+		}
+	})
+}
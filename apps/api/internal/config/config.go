@@ -0,0 +1,508 @@
+// Package config describes the server and the building it runs as a JSON
+// file, such as:
+//
+//	{
+//	  "listen": ":8080",
+//	  "cors": {"origins": ["https://miffed.example"]},
+//	  "pubsub": {"type": "memory"},
+//	  "building": {
+//	    "floors": 20,
+//	    "lobby": 0,
+//	    "parking": {"policy": "lobby", "idle_timeout": "30s"},
+//	    "lifts": [
+//	      {"name": "A", "floor_delay_ms": 1500, "scheduler": "scan"},
+//	      {"name": "B", "floor_delay_ms": 1500, "served_floors": [{"from": 0, "to": 0}, {"from": 10, "to": 19}]}
+//	    ],
+//	    "access": [
+//	      {"floor": 19, "credentials": ["badge-1"], "public": [{"days": ["monday"], "from": "08:00", "to": "18:00"}]}
+//	    ]
+//	  }
+//	}
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+type Config struct {
+	// Listen is the address the server listens on.
+	Listen   string   `json:"listen"`
+	CORS     CORS     `json:"cors"`
+	PubSub   PubSub   `json:"pubsub"`
+	Building Building `json:"building"`
+}
+
+type CORS struct {
+	// Origins may make cross-origin requests and open the socket. "*" allows
+	// any origin.
+	Origins []string `json:"origins"`
+}
+
+// PubSubMemory delivers events within the server's own process.
+const PubSubMemory = "memory"
+
+type PubSub struct {
+	// Type is the pubsub implementation. Only PubSubMemory is supported.
+	Type string `json:"type"`
+}
+
+type Building struct {
+	// Floors is the number of floors, numbered up from LowestFloor. Lifts and
+	// requests are checked against it if it is set.
+	Floors int `json:"floors"`
+	// LowestFloor is negative for a building with basements.
+	LowestFloor int     `json:"lowest_floor"`
+	Lobby       int     `json:"lobby"`
+	Parking     Parking `json:"parking"`
+	// MaxPendingStops caps the stops each lift may have pending, or is 0 for
+	// no cap.
+	MaxPendingStops int          `json:"max_pending_stops"`
+	Lifts           []Lift       `json:"lifts"`
+	Access          []AccessRule `json:"access"`
+}
+
+type Parking struct {
+	Policy      lift.ParkingPolicy `json:"policy"`
+	IdleTimeout Duration           `json:"idle_timeout"`
+}
+
+// ParkingConfig is where the building's lifts park when left idle.
+func (b Building) ParkingConfig() lift.ParkingConfig {
+	return lift.ParkingConfig{
+		Policy:      b.Parking.Policy,
+		IdleTimeout: b.Parking.IdleTimeout.Duration,
+		Lobby:       b.Lobby,
+		Floors:      b.Floors,
+	}
+}
+
+// Lift is a lift the building starts with. Name identifies it in the config,
+// and must be unique.
+type Lift struct {
+	Name         string         `json:"name"`
+	Floor        int            `json:"floor"`
+	FloorDelayMs int            `json:"floor_delay_ms"`
+	DoorDwellMs  int            `json:"door_dwell_ms"`
+	Scheduler    lift.Scheduler `json:"scheduler"`
+	ServedFloors []FloorRange   `json:"served_floors"`
+	Decks        int            `json:"decks"`
+	Shaft        string         `json:"shaft"`
+}
+
+type FloorRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (l Lift) LiftConfig() lift.LiftConfig {
+	cfg := lift.LiftConfig{
+		Floor:        l.Floor,
+		FloorDelayMs: l.FloorDelayMs,
+		DoorDwellMs:  l.DoorDwellMs,
+		Scheduler:    l.Scheduler,
+		Decks:        l.Decks,
+		Shaft:        l.Shaft,
+	}
+	for _, r := range l.ServedFloors {
+		cfg.ServedFloors = append(cfg.ServedFloors, lift.FloorRange{From: r.From, To: r.To})
+	}
+	return cfg
+}
+
+// AccessRule restricts Floor to passengers holding one of Credentials, except
+// while one of its Public schedules is in effect.
+type AccessRule struct {
+	Floor       int        `json:"floor"`
+	Credentials []string   `json:"credentials"`
+	Public      []Schedule `json:"public"`
+}
+
+// Schedule is a daily window of time, such as from "08:00" to "18:00". A
+// window whose To is before its From runs overnight.
+type Schedule struct {
+	// Days are the days the window starts on, or every day if empty.
+	Days []Weekday `json:"days"`
+	From TimeOfDay `json:"from"`
+	To   TimeOfDay `json:"to"`
+}
+
+// AccessRules are the building's access rules, as the lift service takes
+// them.
+func (b Building) AccessRules() []lift.AccessRule {
+	var rules []lift.AccessRule
+	for _, r := range b.Access {
+		rule := lift.AccessRule{Floor: r.Floor, Credentials: r.Credentials}
+		for _, s := range r.Public {
+			schedule := lift.Schedule{From: s.From.Duration, To: s.To.Duration}
+			for _, day := range s.Days {
+				schedule.Days = append(schedule.Days, day.Weekday)
+			}
+			rule.Public = append(rule.Public, schedule)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Weekday is a time.Weekday written as its lower case name, such as
+// "monday".
+type Weekday struct {
+	time.Weekday
+}
+
+func (d *Weekday) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`must be a day such as "monday"`)
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(s, day.String()) {
+			d.Weekday = day
+			return nil
+		}
+	}
+	return fmt.Errorf(`must be a day such as "monday": %q`, s)
+}
+
+func (d Weekday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.ToLower(d.String()))
+}
+
+// TimeOfDay is the time since midnight, written as a string such as "08:30".
+type TimeOfDay struct {
+	time.Duration
+}
+
+func (t *TimeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`must be a time such as "08:30"`)
+	}
+	parsed, err := time.Parse("15:04", s)
+	if err != nil {
+		return fmt.Errorf(`must be a time such as "08:30": %q`, s)
+	}
+	t.Duration = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	return nil
+}
+
+func (t TimeOfDay) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("%02d:%02d", int(t.Hours()), int(t.Minutes())%60))
+}
+
+// Duration is a time.Duration written as a string, such as "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return errors.New(`must be a duration such as "30s"`)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf(`must be a duration such as "30s": %q`, s)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default is the config of a server with no config file: listening on :8080,
+// open to any origin, with an empty building.
+func Default() Config {
+	return Config{
+		Listen: ":8080",
+		CORS:   CORS{Origins: []string{"*"}},
+		PubSub: PubSub{Type: PubSubMemory},
+		Building: Building{
+			Parking: Parking{Policy: lift.ParkingNone, IdleTimeout: Duration{30 * time.Second}},
+		},
+	}
+}
+
+var ErrInvalid = errors.New("invalid config")
+
+// InvalidError lists every problem found with a config. It matches
+// ErrInvalid.
+type InvalidError struct {
+	Source   string
+	Problems []string
+}
+
+func (e *InvalidError) Error() string {
+	source := ""
+	if e.Source != "" {
+		source = " " + e.Source
+	}
+	return fmt.Sprintf("%s%s:\n  %s", ErrInvalid, source, strings.Join(e.Problems, "\n  "))
+}
+
+func (e *InvalidError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Load reads the config at path over the defaults, then applies any
+// overrides from the environment, looked up with getenv. The config is not
+// validated, so that callers can apply overrides of their own first.
+func Load(path string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+	if path != "" {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, err
+		}
+		if err := decode(bs, &cfg); err != nil {
+			return cfg, &InvalidError{Source: path, Problems: []string{err.Error()}}
+		}
+	}
+	if err := cfg.applyEnv(getenv); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func decode(bs []byte, cfg *Config) error {
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(cfg)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the config")
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return nil
+	case err == io.EOF:
+		return errors.New("file is empty")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("line %d: %w", line(bs, syntaxErr.Offset), err)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("%s: must be %s", typeErr.Field, typeErr.Type)
+	}
+	return err
+}
+
+// line is the line number of offset in bs.
+func line(bs []byte, offset int64) int {
+	if offset > int64(len(bs)) {
+		offset = int64(len(bs))
+	}
+	return bytes.Count(bs[:offset], []byte("\n")) + 1
+}
+
+// envOverrides are the environment variables that override a config's
+// settings.
+var envOverrides = []struct {
+	name string
+	set  func(cfg *Config, value string) error
+}{
+	{"MIFFED_LISTEN", func(cfg *Config, v string) error {
+		cfg.Listen = v
+		return nil
+	}},
+	{"MIFFED_CORS_ORIGINS", func(cfg *Config, v string) error {
+		cfg.CORS.Origins = strings.Split(v, ",")
+		return nil
+	}},
+	{"MIFFED_PUBSUB", func(cfg *Config, v string) error {
+		cfg.PubSub.Type = v
+		return nil
+	}},
+	{"MIFFED_FLOORS", func(cfg *Config, v string) (err error) {
+		cfg.Building.Floors, err = strconv.Atoi(v)
+		return err
+	}},
+	{"MIFFED_LOBBY", func(cfg *Config, v string) (err error) {
+		cfg.Building.Lobby, err = strconv.Atoi(v)
+		return err
+	}},
+	{"MIFFED_PARKING", func(cfg *Config, v string) error {
+		cfg.Building.Parking.Policy = lift.ParkingPolicy(v)
+		return nil
+	}},
+	{"MIFFED_PARK_AFTER", func(cfg *Config, v string) (err error) {
+		cfg.Building.Parking.IdleTimeout.Duration, err = time.ParseDuration(v)
+		return err
+	}},
+	{"MIFFED_MAX_STOPS", func(cfg *Config, v string) (err error) {
+		cfg.Building.MaxPendingStops, err = strconv.Atoi(v)
+		return err
+	}},
+}
+
+func (cfg *Config) applyEnv(getenv func(string) string) error {
+	var problems []string
+	for _, env := range envOverrides {
+		v := getenv(env.name)
+		if v == "" {
+			continue
+		}
+		if err := env.set(cfg, v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s=%q: %v", env.name, v, err))
+		}
+	}
+	if problems != nil {
+		return &InvalidError{Source: "from the environment", Problems: problems}
+	}
+	return nil
+}
+
+// Validate checks the config describes a server that can start, returning
+// an InvalidError listing every problem if not.
+func (cfg Config) Validate() error {
+	v := validator{}
+	if _, _, err := net.SplitHostPort(cfg.Listen); err != nil {
+		v.add("listen", "must be an address such as \":8080\"")
+	}
+	if len(cfg.CORS.Origins) == 0 {
+		v.add("cors.origins", "must list at least one origin, or \"*\"")
+	}
+	for i, origin := range cfg.CORS.Origins {
+		if strings.TrimSpace(origin) == "" {
+			v.add(fmt.Sprintf("cors.origins[%d]", i), "must not be empty")
+		}
+	}
+	if cfg.PubSub.Type != PubSubMemory {
+		v.add("pubsub.type", "must be %q", PubSubMemory)
+	}
+	cfg.Building.validate(&v)
+	if v.problems != nil {
+		return &InvalidError{Problems: v.problems}
+	}
+	return nil
+}
+
+// HighestFloor is the building's top floor, if its floors are known.
+func (b Building) HighestFloor() (int, bool) {
+	return b.LowestFloor + b.Floors - 1, b.Floors > 0
+}
+
+func (b Building) validate(v *validator) {
+	v.lowest, v.floors = b.LowestFloor, b.Floors
+	if b.Floors < 0 {
+		v.add("building.floors", "must not be negative")
+		v.floors = 0
+	}
+	v.floor("building.lobby", b.Lobby)
+	if _, err := lift.ParseParkingPolicy(string(b.Parking.Policy)); err != nil {
+		v.add("building.parking.policy", "must be one of %s", join(lift.ParkingPolicies))
+	}
+	if b.Parking.IdleTimeout.Duration < 0 {
+		v.add("building.parking.idle_timeout", "must not be negative")
+	}
+	if b.Parking.Policy == lift.ParkingZones && b.Floors == 0 {
+		v.add("building.floors", "must be set to park in zones")
+	}
+	if b.MaxPendingStops < 0 {
+		v.add("building.max_pending_stops", "must not be negative")
+	}
+
+	names := make(map[string]bool)
+	shafts := make(map[string]int)
+	for i, l := range b.Lifts {
+		field := fmt.Sprintf("building.lifts[%d]", i)
+		if l.Name == "" {
+			v.add(field+".name", "is required")
+		} else if names[l.Name] {
+			v.add(field+".name", "%q is already the name of another lift", l.Name)
+		}
+		names[l.Name] = true
+		l.validate(v, field)
+		if l.Decks == 2 && l.Shaft != "" {
+			v.add(field+".shaft", "must not be set for a double-deck lift")
+		} else if l.Shaft != "" {
+			if shafts[l.Shaft]++; shafts[l.Shaft] == 3 {
+				v.add(field+".shaft", "%q already has two lifts", l.Shaft)
+			}
+		}
+	}
+	for i, r := range b.Access {
+		r.validate(v, fmt.Sprintf("building.access[%d]", i))
+	}
+}
+
+func (r AccessRule) validate(v *validator, field string) {
+	v.floor(field+".floor", r.Floor)
+	for i, c := range r.Credentials {
+		if c == "" {
+			v.add(fmt.Sprintf("%s.credentials[%d]", field, i), "must not be empty")
+		}
+	}
+	for i, s := range r.Public {
+		if s.From == s.To {
+			v.add(fmt.Sprintf("%s.public[%d]", field, i), "from and to must differ")
+		}
+	}
+}
+
+func (l Lift) validate(v *validator, field string) {
+	v.floor(field+".floor", l.Floor)
+	if l.FloorDelayMs < 0 {
+		v.add(field+".floor_delay_ms", "must not be negative")
+	}
+	if l.DoorDwellMs < 0 {
+		v.add(field+".door_dwell_ms", "must not be negative")
+	}
+	if l.Scheduler != "" && !slices.Contains(lift.Schedulers, l.Scheduler) {
+		v.add(field+".scheduler", "must be one of %s", join(lift.Schedulers))
+	}
+	served := len(l.ServedFloors) == 0
+	for i, r := range l.ServedFloors {
+		rangeField := fmt.Sprintf("%s.served_floors[%d]", field, i)
+		v.floor(rangeField+".from", r.From)
+		v.floor(rangeField+".to", r.To)
+		if r.From > r.To {
+			v.add(rangeField, "from must not be above to")
+		}
+		served = served || (l.Floor >= r.From && l.Floor <= r.To)
+	}
+	if !served {
+		v.add(field+".floor", "must be one of the lift's served floors")
+	}
+	if l.Decks < 0 || l.Decks > 2 {
+		v.add(field+".decks", "must be 1 or 2")
+	}
+}
+
+type validator struct {
+	lowest   int
+	floors   int
+	problems []string
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.problems = append(v.problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+// floor checks floor is in the building, if its floors are known.
+func (v *validator) floor(field string, floor int) {
+	if v.floors > 0 && (floor < v.lowest || floor >= v.lowest+v.floors) {
+		v.add(field, "must be between %d and %d", v.lowest, v.lowest+v.floors-1)
+	}
+}
+
+func join[T ~string](all []T) string {
+	names := make([]string, len(all))
+	for i, s := range all {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "miffed.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(name string) string {
+		return vars[name]
+	}
+}
+
+func Test_Load(t *testing.T) {
+	t.Run("a file is read over the defaults", func(t *testing.T) {
+		path := writeConfig(t, `{
+			"listen": "127.0.0.1:9000",
+			"building": {
+				"floors": 10,
+				"parking": {"policy": "lobby"},
+				"lifts": [
+					{"name": "A", "floor_delay_ms": 1500, "scheduler": "scan"},
+					{"name": "B", "floor": 5, "served_floors": [{"from": 0, "to": 0}, {"from": 5, "to": 9}]}
+				],
+				"access": [
+					{"floor": 9, "credentials": ["badge-1"], "public": [{"days": ["monday", "Friday"], "from": "08:00", "to": "18:30"}]}
+				]
+			}
+		}`)
+		cfg, err := Load(path, env(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Listen != "127.0.0.1:9000" || !reflect.DeepEqual(cfg.CORS.Origins, []string{"*"}) || cfg.PubSub.Type != PubSubMemory {
+			t.Errorf("expected the listen address to be read and the rest defaulted, got %+v", cfg)
+		}
+		want := lift.ParkingConfig{Policy: lift.ParkingLobby, IdleTimeout: 30 * time.Second, Floors: 10}
+		if got := cfg.Building.ParkingConfig(); got != want {
+			t.Errorf("expected parking %+v, got %+v", want, got)
+		}
+		wantLift := lift.LiftConfig{Floor: 5, ServedFloors: []lift.FloorRange{{From: 0, To: 0}, {From: 5, To: 9}}}
+		if got := cfg.Building.Lifts[1].LiftConfig(); !reflect.DeepEqual(got, wantLift) {
+			t.Errorf("expected lift %+v, got %+v", wantLift, got)
+		}
+		wantAccess := []lift.AccessRule{{
+			Floor:       9,
+			Credentials: []string{"badge-1"},
+			Public:      []lift.Schedule{{Days: []time.Weekday{time.Monday, time.Friday}, From: 8 * time.Hour, To: 18*time.Hour + 30*time.Minute}},
+		}}
+		if got := cfg.Building.AccessRules(); !reflect.DeepEqual(got, wantAccess) {
+			t.Errorf("expected access rules %+v, got %+v", wantAccess, got)
+		}
+	})
+
+	t.Run("the environment overrides the file", func(t *testing.T) {
+		path := writeConfig(t, `{"listen": ":9000", "building": {"floors": 10}}`)
+		cfg, err := Load(path, env(map[string]string{
+			"MIFFED_LISTEN":       ":9001",
+			"MIFFED_CORS_ORIGINS": "https://a.example,https://b.example",
+			"MIFFED_PARK_AFTER":   "1m",
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Listen != ":9001" || len(cfg.CORS.Origins) != 2 || cfg.Building.Parking.IdleTimeout.Duration != time.Minute || cfg.Building.Floors != 10 {
+			t.Errorf("expected overrides to apply, got %+v", cfg)
+		}
+
+		_, err = Load("", env(map[string]string{"MIFFED_FLOORS": "ten"}))
+		if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), "MIFFED_FLOORS") {
+			t.Errorf("expected an invalid override to be reported, got %v", err)
+		}
+	})
+
+	t.Run("malformed files are reported", func(t *testing.T) {
+		tests := []struct {
+			contents string
+			want     string
+		}{
+			{"", "file is empty"},
+			{"{\n\"listen\": \":8080\",\n}", "line 3"},
+			{`{"building": {"floors": "ten"}}`, "building.floors: must be int"},
+			{`{"building": {"lifts": [{"name": "A", "colour": "red"}]}}`, `unknown field "colour"`},
+			{`{"building": {"parking": {"idle_timeout": 30}}}`, "must be a duration"},
+			{`{"building": {"access": [{"floor": 1, "public": [{"days": ["someday"]}]}]}}`, "must be a day"},
+			{`{"building": {"access": [{"floor": 1, "public": [{"from": "8am"}]}]}}`, "must be a time"},
+		}
+		for _, test := range tests {
+			_, err := Load(writeConfig(t, test.contents), env(nil))
+			if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("%q: expected an error containing %q, got %v", test.contents, test.want, err)
+			}
+		}
+		if _, err := Load(filepath.Join(t.TempDir(), "missing.json"), env(nil)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected a missing file to be reported, got %v", err)
+		}
+	})
+}
+
+func Test_Validate(t *testing.T) {
+	cfg := Default()
+	cfg.Listen = "8080"
+	cfg.PubSub.Type = "kafka"
+	cfg.Building = Building{
+		Floors:  10,
+		Lobby:   12,
+		Parking: Parking{Policy: "valet"},
+		Lifts: []Lift{
+			{Name: "A", Floor: 10, Scheduler: "random"},
+			{Name: "A", Floor: 2, ServedFloors: []FloorRange{{From: 5, To: 9}}, Decks: 3},
+			{Floor: 0, FloorDelayMs: -1, Shaft: "east"},
+			{Name: "C", Shaft: "east"},
+			{Name: "D", Shaft: "east"},
+			{Name: "E", Decks: 2, Shaft: "west"},
+		},
+		Access: []AccessRule{
+			{Floor: 10, Credentials: []string{""}},
+			{Floor: 3, Public: []Schedule{{From: TimeOfDay{8 * time.Hour}, To: TimeOfDay{8 * time.Hour}}}},
+		},
+	}
+
+	err := cfg.Validate()
+	var invalid *InvalidError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected an InvalidError, got %v", err)
+	}
+	want := []string{
+		`listen: must be an address such as ":8080"`,
+		`pubsub.type: must be "memory"`,
+		"building.lobby: must be between 0 and 9",
+		"building.parking.policy: must be one of none, lobby, zones, demand",
+		"building.lifts[0].floor: must be between 0 and 9",
+		"building.lifts[0].scheduler: must be one of fifo, nearest, scan",
+		`building.lifts[1].name: "A" is already the name of another lift`,
+		"building.lifts[1].floor: must be one of the lift's served floors",
+		"building.lifts[1].decks: must be 1 or 2",
+		"building.lifts[2].name: is required",
+		"building.lifts[2].floor_delay_ms: must not be negative",
+		`building.lifts[4].shaft: "east" already has two lifts`,
+		"building.lifts[5].shaft: must not be set for a double-deck lift",
+		"building.access[0].floor: must be between 0 and 9",
+		"building.access[0].credentials[0]: must not be empty",
+		"building.access[1].public[0]: from and to must differ",
+	}
+	if !reflect.DeepEqual(invalid.Problems, want) {
+		t.Errorf("expected problems\n  %s\ngot\n  %s", strings.Join(want, "\n  "), strings.Join(invalid.Problems, "\n  "))
+	}
+
+	if err := Default().Validate(); err != nil {
+		t.Errorf("expected the defaults to be valid, got %v", err)
+	}
+
+	basements := Default()
+	basements.Building = Building{Floors: 5, LowestFloor: -2, Lobby: -3, Lifts: []Lift{{Name: "A", Floor: -2}}}
+	err = basements.Validate()
+	if !errors.As(err, &invalid) || !reflect.DeepEqual(invalid.Problems, []string{"building.lobby: must be between -2 and 2"}) {
+		t.Errorf("expected floors to be numbered up from the lowest, got %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// ChangeType is what reconciling a building did to one of its lifts.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeUpdated ChangeType = "updated"
+	// ChangeRetired lifts are removed once they have served their calls.
+	ChangeRetired ChangeType = "retired"
+)
+
+var ErrNotApplied = errors.New("config not fully applied")
+
+// NotAppliedError lists why lifts couldn't be changed to match a config. It
+// matches ErrNotApplied, and each of its errors.
+type NotAppliedError struct {
+	Errs []error
+}
+
+func (e *NotAppliedError) Error() string {
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrNotApplied, strings.Join(messages, "; "))
+}
+
+func (e *NotAppliedError) Is(target error) bool {
+	return target == ErrNotApplied
+}
+
+func (e *NotAppliedError) Unwrap() []error {
+	return e.Errs
+}
+
+type Change struct {
+	Lift   string      `json:"lift"`
+	LiftId lift.LiftId `json:"lift_id"`
+	Type   ChangeType  `json:"type"`
+}
+
+// Lift is the lift in b with name.
+func (b Building) Lift(name string) (Lift, bool) {
+	for _, l := range b.Lifts {
+		if l.Name == name {
+			return l, true
+		}
+	}
+	return Lift{}, false
+}
+
+func (l Lift) settings() lift.LiftSettings {
+	return lift.LiftSettings{FloorDelayMs: l.FloorDelayMs, DoorDwellMs: l.DoorDwellMs, Scheduler: l.Scheduler}
+}
+
+// sameCar reports whether a running lift configured as l can be reconfigured
+// as other, rather than being replaced.
+func (l Lift) sameCar(other Lift) bool {
+	return slices.Equal(l.ServedFloors, other.ServedFloors) && max(l.Decks, 1) == max(other.Decks, 1) && l.Shaft == other.Shaft
+}
+
+type reconciled struct {
+	id  lift.LiftId
+	cfg Lift
+}
+
+// Reconciler keeps the lifts of a LiftService in line with a building's
+// config, remembering which lift each configured name was added as.
+type Reconciler struct {
+	svc      *lift.LiftService
+	lifts    []reconciled
+	building Building // the building last applied
+	mx       sync.Mutex
+}
+
+func NewReconciler(svc *lift.LiftService) *Reconciler {
+	return &Reconciler{svc: svc}
+}
+
+// Apply adds the lifts in b that are new, retires those no longer in it and
+// updates the settings of the rest. A lift whose served floors, decks or
+// shaft changed is retired and replaced. The building's access rules are
+// replaced too. Changing the floor a lift starts at has no effect on a
+// running lift, and the building's other settings only take effect on
+// restart.
+//
+// Apply carries on past lifts it fails to change, returning every change it
+// made along with a NotAppliedError. Lifts it couldn't add are tried again
+// by the next Apply, or by RetryWhenRetired.
+func (r *Reconciler) Apply(ctx context.Context, b Building) ([]Change, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.building = b
+	r.svc.SetAccessRules(b.AccessRules()...)
+	var changes []Change
+	var errs []error
+	var running []reconciled
+	for _, cur := range r.lifts {
+		want, ok := b.Lift(cur.cfg.Name)
+		switch {
+		case !ok || !cur.cfg.sameCar(want):
+			if _, err := r.svc.RetireLift(ctx, cur.id); err != nil && !errors.Is(err, lift.ErrLiftNotFound) {
+				errs = append(errs, fmt.Errorf("retiring lift %s: %w", cur.cfg.Name, err))
+				running = append(running, cur)
+				continue
+			}
+			changes = append(changes, Change{Lift: cur.cfg.Name, LiftId: cur.id, Type: ChangeRetired})
+		case cur.cfg.settings() != want.settings():
+			if _, err := r.svc.UpdateLift(ctx, cur.id, want.settings()); err != nil {
+				errs = append(errs, fmt.Errorf("updating lift %s: %w", cur.cfg.Name, err))
+				running = append(running, cur)
+				continue
+			}
+			changes = append(changes, Change{Lift: cur.cfg.Name, LiftId: cur.id, Type: ChangeUpdated})
+			running = append(running, reconciled{id: cur.id, cfg: want})
+		default:
+			running = append(running, reconciled{id: cur.id, cfg: want})
+		}
+	}
+	r.lifts = running
+	added, addErrs := r.addMissing(ctx)
+	return append(changes, added...), notApplied(append(errs, addErrs...))
+}
+
+func notApplied(errs []error) error {
+	if errs == nil {
+		return nil
+	}
+	return &NotAppliedError{Errs: errs}
+}
+
+// RetryWhenRetired adds the lifts of the building last applied that couldn't
+// be added each time a lift is retired, until ctx is done, so that a car
+// replacing one in a shared shaft is added once the old car has gone. Any
+// lifts added, or errors adding them, are passed to report along with the
+// building.
+func (r *Reconciler) RetryWhenRetired(ctx context.Context, subs *lift.SubscriptionManager, report func(Building, []Change, error)) error {
+	id, ch, err := subs.Subscribe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer subs.Unsubscribe(id)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-ch:
+				if ev.EventType != "lift_retired" {
+					continue
+				}
+				r.mx.Lock()
+				changes, errs := r.addMissing(ctx)
+				b := r.building
+				r.mx.Unlock()
+				if changes != nil || errs != nil {
+					report(b, changes, notApplied(errs))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// addMissing adds the lifts of the building last applied that aren't
+// running. It must be called with r.mx held.
+func (r *Reconciler) addMissing(ctx context.Context) ([]Change, []error) {
+	var changes []Change
+	var errs []error
+	for _, want := range r.building.Lifts {
+		if slices.ContainsFunc(r.lifts, func(cur reconciled) bool { return cur.cfg.Name == want.Name }) {
+			continue
+		}
+		added, err := r.svc.AddLift(ctx, want.LiftConfig())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("adding lift %s: %w", want.Name, err))
+			continue
+		}
+		changes = append(changes, Change{Lift: want.Name, LiftId: added.Id, Type: ChangeAdded})
+		r.lifts = append(r.lifts, reconciled{id: added.Id, cfg: want})
+	}
+	return changes, errs
+}
+
+// Watch calls reload whenever the file at path is modified, checking every
+// interval until ctx is done.
+func Watch(ctx context.Context, path string, interval time.Duration, reload func()) {
+	type version struct {
+		modified time.Time
+		size     int64
+	}
+	stat := func() version {
+		info, err := os.Stat(path)
+		if err != nil {
+			return version{}
+		}
+		return version{info.ModTime(), info.Size()}
+	}
+	last := stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if v := stat(); v != last {
+				last = v
+				reload()
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Reconciler(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *lift.LiftService, *Reconciler) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		return ctx, svc, NewReconciler(svc)
+	}
+	types := func(changes []Change) map[string]ChangeType {
+		got := make(map[string]ChangeType)
+		for _, c := range changes {
+			got[c.Lift] = c.Type
+		}
+		return got
+	}
+	waitForLifts := func(t *testing.T, svc *lift.LiftService, n int) []lift.Lift {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			lifts, _ := svc.GetLifts(context.Background())
+			if len(lifts) == n {
+				return lifts
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d lifts, got %+v", n, lifts)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	t.Run("lifts are added, updated and retired to match the config", func(t *testing.T) {
+		ctx, svc, r := setup(t)
+		changes, err := r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", FloorDelayMs: 10},
+			{Name: "B", FloorDelayMs: 10},
+			{Name: "C", FloorDelayMs: 10, ServedFloors: []FloorRange{{From: 0, To: 5}}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]ChangeType{"A": ChangeAdded, "B": ChangeAdded, "C": ChangeAdded}; !reflect.DeepEqual(types(changes), want) {
+			t.Fatalf("expected %v, got %v", want, types(changes))
+		}
+		ids := make(map[string]lift.LiftId)
+		for _, c := range changes {
+			ids[c.Lift] = c.LiftId
+		}
+
+		changes, err = r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", FloorDelayMs: 10},
+			{Name: "C", FloorDelayMs: 20, ServedFloors: []FloorRange{{From: 0, To: 5}}},
+			{Name: "D"},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]ChangeType{"B": ChangeRetired, "C": ChangeUpdated, "D": ChangeAdded}; !reflect.DeepEqual(types(changes), want) {
+			t.Errorf("expected %v, got %v", want, types(changes))
+		}
+		lifts := waitForLifts(t, svc, 3)
+		for _, l := range lifts {
+			if l.Id == ids["B"] {
+				t.Errorf("expected lift B to be removed")
+			}
+		}
+
+		changes, _ = r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", FloorDelayMs: 10},
+			{Name: "C", FloorDelayMs: 20, ServedFloors: []FloorRange{{From: 0, To: 9}}},
+			{Name: "D"},
+		}})
+		if len(changes) != 2 || changes[0] != (Change{Lift: "C", LiftId: ids["C"], Type: ChangeRetired}) || changes[1].Type != ChangeAdded {
+			t.Errorf("expected lift C to be replaced, got %+v", changes)
+		}
+		if changes, _ := r.Apply(ctx, Building{Lifts: []Lift{{Name: "A", FloorDelayMs: 10, Floor: 3}}}); len(types(changes)) != 2 {
+			t.Errorf("expected only C and D to be retired, got %+v", changes)
+		}
+		waitForLifts(t, svc, 1)
+	})
+
+	t.Run("a car replacing one in a shared shaft is added once the old car has gone", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		svc := lift.NewLiftService(ctx, ps)
+		r := NewReconciler(svc)
+		reported := make(chan []Change, 1)
+		err := r.RetryWhenRetired(ctx, lift.NewSubscriptionManager(ctx, ps), func(_ Building, changes []Change, err error) {
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			reported <- changes
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, _ := r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", FloorDelayMs: 20, Shaft: "east"},
+			{Name: "B", Floor: 9, Shaft: "east"},
+		}})
+		// A is busy, so is still in the shaft when it is replaced
+		svc.CallLift(ctx, changes[0].LiftId, 3)
+		_, err = r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", FloorDelayMs: 20, Shaft: "east", ServedFloors: []FloorRange{{From: 0, To: 5}}},
+			{Name: "B", Floor: 9, Shaft: "east"},
+		}})
+		if !errors.Is(err, lift.ErrShaftFull) {
+			t.Fatalf("expected the replacement to wait for the shaft, got %v", err)
+		}
+
+		select {
+		case changes := <-reported:
+			if len(changes) != 1 || changes[0].Lift != "A" || changes[0].Type != ChangeAdded {
+				t.Errorf("expected A to be added, got %+v", changes)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected A to be added once the old car retired")
+		}
+		lifts := waitForLifts(t, svc, 2)
+		if !lifts[1].Serves(0) || lifts[1].Serves(6) {
+			t.Errorf("expected the new A to serve floors 0-5, got %+v", lifts[1])
+		}
+	})
+
+	t.Run("access rules are replaced", func(t *testing.T) {
+		ctx, svc, r := setup(t)
+		b := Building{Lifts: []Lift{{Name: "A"}}, Access: []AccessRule{{Floor: 3, Credentials: []string{"badge-1"}}}}
+		changes, _ := r.Apply(ctx, b)
+		id := changes[0].LiftId
+		if _, err := svc.CarCall(ctx, id, 3); !errors.Is(err, lift.ErrAccessDenied) {
+			t.Errorf("expected floor 3 to be restricted, got %v", err)
+		}
+
+		b.Access = nil
+		r.Apply(ctx, b)
+		if _, err := svc.CarCall(ctx, id, 3); err != nil {
+			t.Errorf("expected floor 3 to be open, got %v", err)
+		}
+	})
+
+	t.Run("lifts that can't be added are reported", func(t *testing.T) {
+		ctx, svc, r := setup(t)
+		changes, err := r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", Floor: 5, Shaft: "east"},
+			{Name: "B", Floor: 2, Shaft: "east"},
+			{Name: "C"},
+		}})
+		if !errors.Is(err, ErrNotApplied) || !errors.Is(err, lift.ErrShaftOrder) {
+			t.Errorf("expected a shaft order error, got %v", err)
+		}
+		if want := map[string]ChangeType{"A": ChangeAdded, "C": ChangeAdded}; !reflect.DeepEqual(types(changes), want) {
+			t.Errorf("expected %v, got %v", want, types(changes))
+		}
+
+		// B is tried again on the next reload
+		changes, err = r.Apply(ctx, Building{Lifts: []Lift{
+			{Name: "A", Floor: 5, Shaft: "east"},
+			{Name: "B", Floor: 8, Shaft: "east"},
+			{Name: "C"},
+		}})
+		if err != nil || len(changes) != 1 || changes[0].Lift != "B" {
+			t.Errorf("expected B to be added, got %+v, %v", changes, err)
+		}
+		waitForLifts(t, svc, 3)
+	})
+}
+
+func Test_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := writeConfig(t, `{}`)
+	reloads := make(chan struct{}, 10)
+	go Watch(ctx, path, 5*time.Millisecond, func() { reloads <- struct{}{} })
+
+	select {
+	case <-reloads:
+		t.Fatal("expected no reload before the file changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := os.WriteFile(path, []byte(`{"listen": ":9000"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reload once the file changed")
+	}
+}
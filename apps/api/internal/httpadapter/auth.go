@@ -0,0 +1,272 @@
+package httpadapter
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Role is what an authenticated client may do. Each role can do everything
+// the roles before it can.
+type Role string
+
+const (
+	// RoleViewer can read lifts, stats and events.
+	RoleViewer Role = "viewer"
+	// RolePassenger can also call lifts.
+	RolePassenger Role = "passenger"
+	// RoleOperator can also add lifts, take them out of service and use the
+	// admin routes.
+	RoleOperator Role = "operator"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RolePassenger: 2, RoleOperator: 3}
+
+var ErrUnknownRole = errors.New("unknown role")
+
+// ParseRole checks s is a known role.
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRanks[Role(s)]; !ok {
+		return "", ErrUnknownRole
+	}
+	return Role(s), nil
+}
+
+func (r Role) includes(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// routeRoles is the least role needed for each route. Routes not listed need
+// RoleOperator.
+var routeRoles = map[string]Role{
+	"GET /lift":          RoleViewer,
+	"GET /lift/{id}":     RoleViewer,
+	"GET /lift/{id}/eta": RoleViewer,
+	"GET /eta":           RoleViewer,
+	"GET /stats":         RoleViewer,
+	"GET /metrics":       RoleViewer,
+	"GET /openapi.json":  RoleViewer,
+	"/socket":            RoleViewer,
+
+	"POST /lift/{id}/call":     RolePassenger,
+	"POST /lift/{id}/car-call": RolePassenger,
+	"POST /call":               RolePassenger,
+	"POST /calls/batch":        RolePassenger,
+}
+
+var (
+	errUnauthenticated = errors.New("missing or invalid bearer token")
+	errForbidden       = errors.New("not permitted")
+	errInvalidToken    = errors.New("invalid token")
+	errTokenExpired    = errors.New("token expired")
+)
+
+// Claims are the JWT claims the API reads.
+type Claims struct {
+	Subject   string `json:"sub,omitempty"`
+	Role      Role   `json:"role"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+}
+
+type authOptions struct {
+	keys   map[string]Role
+	secret []byte
+	roles  map[string]Role
+	limit  RateLimit
+}
+
+type AuthOption func(opts *authOptions)
+
+// WithAPIKey accepts key as a bearer token for role.
+func WithAPIKey(key string, role Role) AuthOption {
+	return func(opts *authOptions) {
+		opts.keys[key] = role
+	}
+}
+
+// WithJWTSecret accepts JWTs signed with HS256 using secret, granting the
+// role in their claims.
+func WithJWTSecret(secret []byte) AuthOption {
+	return func(opts *authOptions) {
+		opts.secret = secret
+	}
+}
+
+// WithRouteRole sets the least role needed for a route, given as the pattern
+// it was registered with.
+func WithRouteRole(pattern string, role Role) AuthOption {
+	return func(opts *authOptions) {
+		opts.roles[pattern] = role
+	}
+}
+
+// WithRateLimit limits how fast each client may make requests, as
+// NewRateLimit does, telling authenticated clients apart by their JWT's
+// subject, or the API key or role they used. Clients that fail to
+// authenticate are limited by their IP address.
+func WithRateLimit(limit RateLimit) AuthOption {
+	return func(opts *authOptions) {
+		opts.limit = limit
+	}
+}
+
+// NewAuth requires a bearer token with a sufficient role for every route
+// registered on mux, including the /socket upgrade. Browsers can't set
+// headers on websocket upgrades, so the token may be given there as the
+// access_token query parameter instead.
+func NewAuth(mux *http.ServeMux, opts ...AuthOption) http.Handler {
+	o := authOptions{keys: make(map[string]Role), roles: make(map[string]Role)}
+	for pattern, role := range routeRoles {
+		o.roles[pattern] = role
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	rl := newRateLimiter(o.limit)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			// let the mux respond with 404 or 405
+			if rl.allow(w, r) {
+				mux.ServeHTTP(w, r)
+			}
+			return
+		}
+		required, ok := o.roles[pattern]
+		if !ok {
+			required = RoleOperator
+		}
+
+		role, subject, err := o.authenticate(r)
+		if err == nil {
+			ctx := context.WithValue(r.Context(), roleKey{}, role)
+			r = r.WithContext(context.WithValue(ctx, subjectKey{}, subject))
+		}
+		if !rl.allow(w, r) {
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="miffed"`)
+			errResponse(w, 401, err)
+			return
+		}
+		if !role.includes(required) {
+			errResponse(w, 403, errForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type (
+	roleKey    struct{}
+	subjectKey struct{}
+)
+
+// RoleFrom returns the role of the client making a request, if it was
+// authenticated.
+func RoleFrom(ctx context.Context) (Role, bool) {
+	role, ok := ctx.Value(roleKey{}).(Role)
+	return role, ok
+}
+
+// authenticate returns the role of the client making r, and a subject
+// identifying it.
+func (o authOptions) authenticate(r *http.Request) (Role, string, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok && websocketUpgrade(r) {
+		token, ok = r.URL.Query().Get("access_token"), true
+	}
+	if !ok || token == "" {
+		return "", "", errUnauthenticated
+	}
+
+	for key, role := range o.keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(token)) == 1 {
+			sum := sha256.Sum256([]byte(key))
+			return role, "key:" + hex.EncodeToString(sum[:8]), nil
+		}
+	}
+	if o.secret == nil {
+		return "", "", errUnauthenticated
+	}
+	claims, err := verifyToken(o.secret, token, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if _, known := roleRanks[claims.Role]; !known {
+		return "", "", errInvalidToken
+	}
+	if claims.Subject == "" {
+		return claims.Role, "role:" + string(claims.Role), nil
+	}
+	return claims.Role, "sub:" + claims.Subject, nil
+}
+
+func websocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// NewToken signs claims as a JWT using HS256.
+func NewToken(secret []byte, claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + sign(secret, unsigned), nil
+}
+
+func verifyToken(secret []byte, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, errInvalidToken
+	}
+	if !hmac.Equal([]byte(sign(secret, parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return Claims{}, errInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, errInvalidToken
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, errInvalidToken
+	}
+	if claims.ExpiresAt != 0 && !now.Before(time.Unix(claims.ExpiresAt, 0)) {
+		return Claims{}, errTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0)) {
+		return Claims{}, errInvalidToken
+	}
+	return claims, nil
+}
+
+func sign(secret []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func decodeSegment(segment string, v any) error {
+	bs, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
@@ -0,0 +1,133 @@
+package httpadapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Auth(t *testing.T) {
+	secret := []byte("s3cret")
+	setup := func(t *testing.T) (*httptest.Server, lift.Lift) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		svc := lift.NewLiftService(ctx, ps)
+		subs := lift.NewSubscriptionManager(ctx, ps)
+		l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+		mux := http.NewServeMux()
+		mux = NewController(mux, svc)
+		mux = NewSocket(mux, subs)
+		server := httptest.NewServer(NewAuth(mux,
+			WithAPIKey("viewer-key", RoleViewer),
+			WithAPIKey("operator-key", RoleOperator),
+			WithJWTSecret(secret),
+		))
+		t.Cleanup(server.Close)
+		return server, l
+	}
+	token := func(t *testing.T, claims Claims) string {
+		tok, err := NewToken(secret, claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	do := func(t *testing.T, server *httptest.Server, method, path, body, token string) int {
+		req, _ := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("routes need a token", func(t *testing.T) {
+		server, _ := setup(t)
+		if got := do(t, server, "GET", "/lift", "", ""); got != 401 {
+			t.Errorf("expected 401, got %d", got)
+		}
+		if got := do(t, server, "GET", "/lift", "", "wrong-key"); got != 401 {
+			t.Errorf("expected 401 for an unknown key, got %d", got)
+		}
+		if got := do(t, server, "GET", "/lift", "", "viewer-key"); got != 200 {
+			t.Errorf("expected 200, got %d", got)
+		}
+	})
+
+	t.Run("only operators can add lifts", func(t *testing.T) {
+		server, _ := setup(t)
+		body := `{"floor":0}`
+		if got := do(t, server, "POST", "/lift", body, "viewer-key"); got != 403 {
+			t.Errorf("expected 403 for a viewer, got %d", got)
+		}
+		if got := do(t, server, "POST", "/lift", body, token(t, Claims{Role: RolePassenger})); got != 403 {
+			t.Errorf("expected 403 for a passenger, got %d", got)
+		}
+		if got := do(t, server, "POST", "/lift", body, "operator-key"); got != 201 {
+			t.Errorf("expected 201 for an operator, got %d", got)
+		}
+	})
+
+	t.Run("passengers can call lifts", func(t *testing.T) {
+		server, l := setup(t)
+		path := "/lift/" + l.Id.String() + "/call"
+		if got := do(t, server, "POST", path, `{"floor":3}`, "viewer-key"); got != 403 {
+			t.Errorf("expected 403 for a viewer, got %d", got)
+		}
+		if got := do(t, server, "POST", path, `{"floor":3}`, token(t, Claims{Role: RolePassenger})); got != 201 {
+			t.Errorf("expected 201 for a passenger, got %d", got)
+		}
+	})
+
+	t.Run("tokens must be valid", func(t *testing.T) {
+		server, _ := setup(t)
+		expired := token(t, Claims{Role: RoleOperator, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+		if got := do(t, server, "GET", "/lift", "", expired); got != 401 {
+			t.Errorf("expected 401 for an expired token, got %d", got)
+		}
+		forged, _ := NewToken([]byte("other"), Claims{Role: RoleOperator})
+		if got := do(t, server, "GET", "/lift", "", forged); got != 401 {
+			t.Errorf("expected 401 for a forged token, got %d", got)
+		}
+		if got := do(t, server, "GET", "/lift", "", token(t, Claims{Role: "admin"})); got != 401 {
+			t.Errorf("expected 401 for an unknown role, got %d", got)
+		}
+		valid := token(t, Claims{Role: RoleViewer, ExpiresAt: time.Now().Add(time.Minute).Unix()})
+		if got := do(t, server, "GET", "/lift", "", valid); got != 200 {
+			t.Errorf("expected 200, got %d", got)
+		}
+	})
+
+	t.Run("the socket upgrade needs a token", func(t *testing.T) {
+		server, _ := setup(t)
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/socket"
+		origin := http.Header{"Origin": {strings.TrimPrefix(server.URL, "http://")}}
+		if _, res, err := websocket.DefaultDialer.Dial(wsURL, origin); err == nil || res.StatusCode != 401 {
+			t.Errorf("expected 401 without a token, got %v", err)
+		}
+		ws, _, err := websocket.DefaultDialer.Dial(wsURL+"?access_token=viewer-key", origin)
+		if err != nil {
+			t.Fatalf("expected the access_token query parameter to be accepted, got %v", err)
+		}
+		ws.Close()
+		origin.Set("Authorization", "Bearer viewer-key")
+		ws, _, err = websocket.DefaultDialer.Dial(wsURL, origin)
+		if err != nil {
+			t.Fatalf("expected a bearer token to be accepted, got %v", err)
+		}
+		ws.Close()
+	})
+}
@@ -0,0 +1,154 @@
+package httpadapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/recording"
+)
+
+// maxBatchSize is the most items a batch request may hold.
+const maxBatchSize = 100
+
+// Batch items are kept raw and decoded one by one, so that a bad item fails
+// alone with an error in its result.
+type createLiftBatchReq struct {
+	Lifts []json.RawMessage `json:"lifts"`
+}
+
+func (req createLiftBatchReq) validate(v *validator) {
+	validateBatch(v, "lifts", len(req.Lifts))
+}
+
+type callBatchReq struct {
+	Calls []json.RawMessage `json:"calls"`
+}
+
+func (req callBatchReq) validate(v *validator) {
+	validateBatch(v, "calls", len(req.Calls))
+}
+
+func validateBatch(v *validator, field string, n int) {
+	if v.required(field, n > 0) && n > maxBatchSize {
+		v.add(field, CodeInvalidValue, "must have at most %d items", maxBatchSize)
+	}
+}
+
+// batchCallReq calls the lift with LiftId to Floor, or without a LiftId
+// dispatches whichever lift is best placed, optionally with a Destination and
+// a Credential for it.
+type batchCallReq struct {
+	LiftId      string `json:"lift_id"`
+	Floor       *int   `json:"floor"`
+	Destination *int   `json:"destination"`
+	Credential  string `json:"credential"`
+}
+
+func (req batchCallReq) validate(v *validator) {
+	if req.LiftId != "" {
+		if _, err := lift.ParseLiftId(req.LiftId); err != nil {
+			v.add("lift_id", CodeInvalidLiftId, "must be a UUID")
+		}
+		if req.Destination != nil {
+			v.add("destination", CodeInvalidValue, "can only be given without lift_id")
+		}
+		if req.Credential != "" {
+			v.add("credential", CodeInvalidValue, "can only be given without lift_id")
+		}
+	}
+	dispatchReq{Floor: req.Floor, Destination: req.Destination}.validate(v)
+}
+
+type liftBatchItemRes struct {
+	Status int            `json:"status"`
+	Lift   *createLiftRes `json:"lift,omitempty"`
+	Error  *errorResponse `json:"error,omitempty"`
+}
+
+type liftBatchRes struct {
+	Results []liftBatchItemRes `json:"results"`
+}
+
+type callBatchItemRes struct {
+	Status int            `json:"status"`
+	Call   *callRes       `json:"call,omitempty"`
+	Error  *errorResponse `json:"error,omitempty"`
+}
+
+type callBatchRes struct {
+	Results []callBatchItemRes `json:"results"`
+}
+
+func itemError(err error) (int, *errorResponse) {
+	status := liftErrStatus(err)
+	res := newErrorResponse(status, err)
+	return status, &res
+}
+
+// createLiftBatchHandler adds lifts in the order given, so lifts sharing a
+// shaft are added lower car first.
+func createLiftBatchHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[createLiftBatchReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		res := liftBatchRes{Results: make([]liftBatchItemRes, len(body.Lifts))}
+		for i, raw := range body.Lifts {
+			item, err := decode[createLiftReq](bytes.NewReader(raw), floors)
+			if err == nil {
+				var l lift.Lift
+				if l, err = addLift(r.Context(), svc, rec, item); err == nil {
+					res.Results[i] = liftBatchItemRes{Status: 201, Lift: &createLiftRes{Id: l.Id, Floor: l.Floor}}
+					continue
+				}
+			}
+			res.Results[i].Status, res.Results[i].Error = itemError(err)
+		}
+		okResponse(w, 200, res)
+	})
+}
+
+func callBatchHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[callBatchReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		res := callBatchRes{Results: make([]callBatchItemRes, len(body.Calls))}
+		for i, raw := range body.Calls {
+			call, legs, err := placeCall(r, svc, rec, raw, floors)
+			if err != nil {
+				res.Results[i].Status, res.Results[i].Error = itemError(err)
+				continue
+			}
+			item := newCallRes(call.Call())
+			item.Legs = newLegsRes(legs)
+			res.Results[i] = callBatchItemRes{Status: 201, Call: &item}
+		}
+		okResponse(w, 200, res)
+	})
+}
+
+// placeCall decodes and places one call of a batch.
+func placeCall(r *http.Request, svc *lift.LiftService, rec *recording.Recorder, raw json.RawMessage, floors floorLimits) (*lift.CallHandle, []lift.Leg, error) {
+	item, err := decode[batchCallReq](bytes.NewReader(raw), floors)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item.LiftId != "" {
+		id, _ := lift.ParseLiftId(item.LiftId)
+		handle, err := svc.CallLift(r.Context(), id, *item.Floor)
+		if err == nil {
+			rec.RecordCommand(recording.Command{Type: recording.CommandCallLift, LiftId: id, Floor: *item.Floor})
+		}
+		return handle, nil, err
+	}
+	return dispatch(r.Context(), svc, rec, *item.Floor, item.Destination, item.Credential)
+}
@@ -0,0 +1,135 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Batch(t *testing.T) {
+	setup := func(t *testing.T) (*http.ServeMux, *lift.LiftService) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		return NewController(http.NewServeMux(), svc, WithFloors(0, 20)), svc
+	}
+	post := func(server http.Handler, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("lifts are added with a result each", func(t *testing.T) {
+		server, svc := setup(t)
+		rec := post(server, "/lift/batch", `{"lifts": [
+			{"floor": 0},
+			{"floor": 30},
+			{"floor": 2, "colour": "red"},
+			{"floor": 5, "shaft": "a"}
+		]}`)
+		if rec.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var res liftBatchRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		want := []struct {
+			status int
+			code   ErrorCode
+		}{{201, ""}, {400, CodeFloorOutOfRange}, {400, CodeUnknownField}, {201, ""}}
+		if len(res.Results) != len(want) {
+			t.Fatalf("expected %d results, got %+v", len(want), res.Results)
+		}
+		for i, w := range want {
+			got := res.Results[i]
+			if got.Status != w.status {
+				t.Errorf("result %d: expected status %d, got %d", i, w.status, got.Status)
+			}
+			if w.code == "" && (got.Lift == nil || got.Error != nil) {
+				t.Errorf("result %d: expected a lift, got %+v", i, got)
+			}
+			if w.code != "" && (got.Error == nil || got.Error.ErrorCode != w.code || got.Lift != nil) {
+				t.Errorf("result %d: expected error %s, got %+v", i, w.code, got)
+			}
+		}
+		if l, err := svc.GetLift(context.Background(), res.Results[3].Lift.Id); err != nil || l.Floor != 5 {
+			t.Errorf("expected the lift to be added at floor 5, got %+v, %v", l, err)
+		}
+		if ls, _ := svc.GetLifts(context.Background()); len(ls) != 2 {
+			t.Errorf("expected 2 lifts, got %d", len(ls))
+		}
+	})
+
+	t.Run("calls are placed with a result each", func(t *testing.T) {
+		server, svc := setup(t)
+		l, _ := svc.AddLift(context.Background(), lift.LiftConfig{Floor: 0})
+		rec := post(server, "/calls/batch", fmt.Sprintf(`{"calls": [
+			{"lift_id": %[1]q, "floor": 3},
+			{"floor": 4},
+			{"floor": 1, "destination": 6},
+			{"lift_id": %[2]q, "floor": 3},
+			{"lift_id": "nope", "floor": 3},
+			{"lift_id": %[1]q, "floor": 3, "destination": 4},
+			{"lift_id": %[1]q, "floor": 3, "credential": "badge-1"},
+			{}
+		]}`, l.Id, lift.NewLiftId()))
+		if rec.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		var res callBatchRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		want := []struct {
+			status int
+			code   ErrorCode
+		}{
+			{201, ""},
+			{201, ""},
+			{201, ""},
+			{404, CodeLiftNotFound},
+			{400, CodeInvalidLiftId},
+			{400, CodeInvalidValue},
+			{400, CodeInvalidValue},
+			{400, CodeMissingField},
+		}
+		if len(res.Results) != len(want) {
+			t.Fatalf("expected %d results, got %+v", len(want), res.Results)
+		}
+		for i, w := range want {
+			got := res.Results[i]
+			if got.Status != w.status {
+				t.Errorf("result %d: expected status %d, got %d", i, w.status, got.Status)
+			}
+			if w.code == "" && (got.Call == nil || got.Call.LiftId != l.Id) {
+				t.Errorf("result %d: expected a call to the lift, got %+v", i, got)
+			}
+			if w.code != "" && (got.Error == nil || got.Error.ErrorCode != w.code) {
+				t.Errorf("result %d: expected error %s, got %+v", i, w.code, got)
+			}
+		}
+		if floor := res.Results[1].Call.Floor; floor != 4 {
+			t.Errorf("expected the dispatched call to floor 4, got %d", floor)
+		}
+	})
+
+	t.Run("batches must hold between 1 and 100 items", func(t *testing.T) {
+		server, _ := setup(t)
+		tooMany := `{"calls": [` + strings.Repeat(`{"floor": 1},`, maxBatchSize) + `{"floor": 1}]}`
+		for path, body := range map[string]string{
+			"/lift/batch":  `{"lifts": []}`,
+			"/calls/batch": tooMany,
+		} {
+			rec := post(server, path, body)
+			var res errorResponse
+			json.NewDecoder(rec.Body).Decode(&res)
+			if rec.Code != 400 || len(res.Fields) != 1 {
+				t.Errorf("%s: expected 400 with one field at fault, got %d %+v", path, rec.Code, res)
+			}
+		}
+	})
+}
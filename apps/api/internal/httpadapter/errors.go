@@ -0,0 +1,165 @@
+package httpadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/leow93/miffed-api/internal/config"
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// ErrorCode is a stable, machine-readable reason a request failed. Clients
+// should branch on it rather than on error messages, which may change.
+type ErrorCode string
+
+// Codes for requests that fail validation. A response lists every field at
+// fault, and its error_code is that of the first.
+const (
+	CodeInvalidJSON     ErrorCode = "invalid_json"
+	CodeRequestTooLarge ErrorCode = "request_too_large"
+	CodeUnknownField    ErrorCode = "unknown_field"
+	CodeInvalidType     ErrorCode = "invalid_type"
+	CodeMissingField    ErrorCode = "missing_field"
+	CodeInvalidValue    ErrorCode = "invalid_value"
+	CodeInvalidLiftId   ErrorCode = "invalid_lift_id"
+	CodeFloorOutOfRange ErrorCode = "floor_out_of_range"
+)
+
+// Codes for requests the lifts can't carry out.
+const (
+	CodeLiftNotFound       ErrorCode = "lift_not_found"
+	CodeFloorNotServed     ErrorCode = "floor_not_served"
+	CodeInvalidFloorRange  ErrorCode = "invalid_floor_range"
+	CodeInvalidDecks       ErrorCode = "invalid_decks"
+	CodeShaftFull          ErrorCode = "shaft_full"
+	CodeShaftOrder         ErrorCode = "shaft_order"
+	CodeShaftDecks         ErrorCode = "shaft_decks"
+	CodeUnknownFault       ErrorCode = "unknown_fault"
+	CodeAccessDenied       ErrorCode = "access_denied"
+	CodeLiftOutOfService   ErrorCode = "lift_out_of_service"
+	CodeLiftRetiring       ErrorCode = "lift_retiring"
+	CodeCallCancelled      ErrorCode = "call_cancelled"
+	CodeEmergencyRecall    ErrorCode = "emergency_recall"
+	CodeIndependentService ErrorCode = "independent_service"
+	CodeTooManyCalls       ErrorCode = "too_many_calls"
+	CodeNoLiftAvailable    ErrorCode = "no_lift_available"
+	CodeNoEstimate         ErrorCode = "no_estimate"
+	CodeNoRoute            ErrorCode = "no_route"
+)
+
+// Codes for everything else.
+const (
+	CodeUnauthenticated     ErrorCode = "unauthenticated"
+	CodeInvalidToken        ErrorCode = "invalid_token"
+	CodeTokenExpired        ErrorCode = "token_expired"
+	CodeForbidden           ErrorCode = "forbidden"
+	CodeRateLimited         ErrorCode = "rate_limited"
+	CodeIdempotencyConflict ErrorCode = "idempotency_conflict"
+	CodeWaitTimedOut        ErrorCode = "wait_timed_out"
+	CodeInvalidConfig       ErrorCode = "invalid_config"
+	CodeUnsupportedMessage  ErrorCode = "unsupported_message"
+	CodeBadRequest          ErrorCode = "bad_request"
+	CodeNotFound            ErrorCode = "not_found"
+	CodeInternal            ErrorCode = "internal_error"
+)
+
+// errorResponse is the body of every error response, and of every error
+// message sent over the socket. Code is the HTTP status.
+type errorResponse struct {
+	Code      int          `json:"code"`
+	ErrorCode ErrorCode    `json:"error_code"`
+	Error     *string      `json:"error"`
+	Fields    []fieldError `json:"fields,omitempty"`
+}
+
+// knownErrors gives the status and code of every error a handler may pass on.
+var knownErrors = []struct {
+	err    error
+	status int
+	code   ErrorCode
+}{
+	{lift.ErrInvalidLiftId, 400, CodeInvalidLiftId},
+	{lift.ErrUnknownFault, 400, CodeUnknownFault},
+	{lift.ErrInvalidFloorRange, 400, CodeInvalidFloorRange},
+	{lift.ErrFloorNotServed, 400, CodeFloorNotServed},
+	{lift.ErrInvalidDecks, 400, CodeInvalidDecks},
+	{lift.ErrShaftDecks, 400, CodeShaftDecks},
+	{lift.ErrAccessDenied, 403, CodeAccessDenied},
+	{lift.ErrLiftNotFound, 404, CodeLiftNotFound},
+	{lift.ErrLiftOutOfService, 409, CodeLiftOutOfService},
+	{lift.ErrLiftRetiring, 409, CodeLiftRetiring},
+	{lift.ErrCallCancelled, 409, CodeCallCancelled},
+	{lift.ErrEmergencyRecall, 409, CodeEmergencyRecall},
+	{lift.ErrIndependentService, 409, CodeIndependentService},
+	{lift.ErrShaftFull, 409, CodeShaftFull},
+	{lift.ErrShaftOrder, 409, CodeShaftOrder},
+	{lift.ErrTooManyCalls, 429, CodeTooManyCalls},
+	{lift.ErrNoLiftAvailable, 503, CodeNoLiftAvailable},
+	{lift.ErrNoEstimate, 503, CodeNoEstimate},
+	{lift.ErrNoRoute, 503, CodeNoRoute},
+
+	{errUnauthenticated, 401, CodeUnauthenticated},
+	{errInvalidToken, 401, CodeInvalidToken},
+	{errTokenExpired, 401, CodeTokenExpired},
+	{errForbidden, 403, CodeForbidden},
+	{errRateLimited, 429, CodeRateLimited},
+	{errIdempotencyConflict, 409, CodeIdempotencyConflict},
+	{errCallWaitTimedOut, 504, CodeWaitTimedOut},
+	{errUnsupportedMessage, 400, CodeUnsupportedMessage},
+	{config.ErrInvalid, 400, CodeInvalidConfig},
+}
+
+// statusCodes are the codes of errors with no more specific code.
+var statusCodes = map[int]ErrorCode{
+	400: CodeBadRequest,
+	401: CodeUnauthenticated,
+	403: CodeForbidden,
+	404: CodeNotFound,
+	413: CodeRequestTooLarge,
+	429: CodeRateLimited,
+}
+
+func liftErrStatus(err error) int {
+	for _, known := range knownErrors {
+		if errors.Is(err, known.err) {
+			return known.status
+		}
+	}
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		return invalid.status()
+	}
+	return 500
+}
+
+func newErrorResponse(status int, err error) errorResponse {
+	message := err.Error()
+	res := errorResponse{Code: status, ErrorCode: CodeInternal, Error: &message}
+	var invalid validationError
+	if errors.As(err, &invalid) && len(invalid) > 0 {
+		res.ErrorCode = invalid[0].Code
+		res.Fields = invalid
+		return res
+	}
+	for _, known := range knownErrors {
+		if errors.Is(err, known.err) {
+			res.ErrorCode = known.code
+			return res
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		res.ErrorCode = code
+	}
+	return res
+}
+
+func errResponse(w http.ResponseWriter, status int, err error) {
+	var tooMany *lift.TooManyCallsError
+	if errors.As(err, &tooMany) {
+		setRetryAfter(w, tooMany.RetryAfter)
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newErrorResponse(status, err))
+}
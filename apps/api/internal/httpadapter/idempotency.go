@@ -0,0 +1,179 @@
+package httpadapter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	// defaultIdempotencyTTL is how long responses are kept for replaying.
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+var errIdempotencyConflict = errors.New("idempotency key already used for a different request")
+
+// idempotentResponse is the response to the first request made with a key.
+// done is closed once it has been written, so that retries arriving while the
+// first request is still in progress wait for it.
+type idempotentResponse struct {
+	hash    [sha256.Size]byte
+	done    chan struct{}
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+type idempotencyStore struct {
+	ttl       time.Duration
+	now       func() time.Time
+	responses map[string]*idempotentResponse
+	mx        sync.Mutex
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{ttl: ttl, now: time.Now, responses: make(map[string]*idempotentResponse)}
+}
+
+// WithIdempotencyTTL sets how long responses to requests with an
+// Idempotency-Key are kept for replaying to retries.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(opts *controllerOptions) {
+		opts.idempotencyTTL = ttl
+	}
+}
+
+// idempotent replays the stored response to a request retried with the same
+// Idempotency-Key header, rather than handling it again. Keys are scoped to
+// the client and path. Reusing a key for a different request is a conflict.
+// Server errors and 429s are not stored, so those requests can be retried.
+func idempotent(store *idempotencyStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			err := validationError{{Field: idempotencyKeyHeader, Code: CodeInvalidValue, Message: "must be at most 255 characters"}}
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			err = decodeError(err)
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		h := sha256.New()
+		io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+		h.Write(body)
+		hash := [sha256.Size]byte(h.Sum(nil))
+		res, first := store.claim(clientKey(r)+" "+r.URL.Path+" "+key, hash)
+		if !first {
+			if res.hash != hash {
+				errResponse(w, 409, errIdempotencyConflict)
+				return
+			}
+			if !res.wait(r) {
+				errResponse(w, 503, r.Context().Err())
+				return
+			}
+			res.replay(w)
+			return
+		}
+
+		capture := &capturingWriter{ResponseWriter: w}
+		next.ServeHTTP(capture, r)
+		store.complete(res, capture)
+	})
+}
+
+// claim returns the response stored for key, or a new one to fill in if
+// this is the first request made with it.
+func (s *idempotencyStore) claim(key string, hash [sha256.Size]byte) (*idempotentResponse, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	now := s.now()
+	if res, ok := s.responses[key]; ok && (res.expires.IsZero() || now.Before(res.expires)) {
+		return res, false
+	}
+	for k, res := range s.responses {
+		if !res.expires.IsZero() && !now.Before(res.expires) {
+			delete(s.responses, k)
+		}
+	}
+	res := &idempotentResponse{hash: hash, done: make(chan struct{})}
+	s.responses[key] = res
+	return res, true
+}
+
+func (s *idempotencyStore) complete(res *idempotentResponse, capture *capturingWriter) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	res.status = capture.statusCode()
+	res.header = capture.Header().Clone()
+	res.body = capture.body.Bytes()
+	res.expires = s.now().Add(s.ttl)
+	if res.status >= 500 || res.status == 429 {
+		// let the client try again
+		res.expires = s.now()
+	}
+	close(res.done)
+}
+
+func (res *idempotentResponse) wait(r *http.Request) bool {
+	select {
+	case <-res.done:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (res *idempotentResponse) replay(w http.ResponseWriter) {
+	for name, values := range res.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(res.status)
+	w.Write(res.body)
+}
+
+// capturingWriter keeps a copy of the response it writes.
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *capturingWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *capturingWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = 200
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *capturingWriter) statusCode() int {
+	if c.status == 0 {
+		return 200
+	}
+	return c.status
+}
@@ -0,0 +1,166 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Idempotency(t *testing.T) {
+	post := func(server http.Handler, path, body, key, addr string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		if addr != "" {
+			req.RemoteAddr = addr
+		}
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	setup := func(t *testing.T) (*http.ServeMux, *lift.LiftService) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		return NewController(http.NewServeMux(), svc), svc
+	}
+	lifts := func(t *testing.T, svc *lift.LiftService) int {
+		ls, _ := svc.GetLifts(context.Background())
+		return len(ls)
+	}
+
+	t.Run("retried lift creation is replayed", func(t *testing.T) {
+		server, svc := setup(t)
+		first := post(server, "/lift", `{"floor": 2}`, "abc", "")
+		retry := post(server, "/lift", `{"floor": 2}`, "abc", "")
+		if first.Code != 201 || retry.Code != 201 {
+			t.Fatalf("expected 201s, got %d and %d", first.Code, retry.Code)
+		}
+		if first.Body.String() != retry.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("expected the first response to be replayed, got %s and %s", first.Body, retry.Body)
+		}
+		if n := lifts(t, svc); n != 1 {
+			t.Errorf("expected 1 lift, got %d", n)
+		}
+		post(server, "/lift", `{"floor": 2}`, "", "")
+		post(server, "/lift", `{"floor": 2}`, "abc", "192.0.2.2:1234")
+		if n := lifts(t, svc); n != 3 {
+			t.Errorf("expected requests without the key, or from other clients, to be handled, got %d lifts", n)
+		}
+	})
+
+	t.Run("keys can't be reused for different requests", func(t *testing.T) {
+		server, _ := setup(t)
+		post(server, "/lift", `{"floor": 2}`, "abc", "")
+		rec := post(server, "/lift", `{"floor": 3}`, "abc", "")
+		var res errorResponse
+		json.NewDecoder(rec.Body).Decode(&res)
+		if rec.Code != 409 || res.ErrorCode != CodeIdempotencyConflict {
+			t.Errorf("expected 409 %s, got %d %+v", CodeIdempotencyConflict, rec.Code, res)
+		}
+	})
+
+	t.Run("retried calls are replayed", func(t *testing.T) {
+		server, svc := setup(t)
+		l, _ := svc.AddLift(context.Background(), lift.LiftConfig{Floor: 0})
+		path := "/lift/" + l.Id.String() + "/call"
+		first := post(server, path, `{"floor": 4}`, "call-1", "")
+		retry := post(server, path, `{"floor": 4}`, "call-1", "")
+		var a, b callRes
+		json.NewDecoder(first.Body).Decode(&a)
+		json.NewDecoder(retry.Body).Decode(&b)
+		if first.Code != 201 || a.Id != b.Id {
+			t.Errorf("expected the same call, got %+v and %+v", a, b)
+		}
+	})
+
+	t.Run("keys belong to the verified client, not its token", func(t *testing.T) {
+		mux, svc := setup(t)
+		secret := []byte("s3cret")
+		server := NewAuth(mux, WithJWTSecret(secret))
+		token := func(subject string, expires time.Time) string {
+			tok, _ := NewToken(secret, Claims{Subject: subject, Role: RoleOperator, ExpiresAt: expires.Unix()})
+			return tok
+		}
+		postAs := func(token string) int {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/lift", strings.NewReader(`{"floor": 2}`))
+			req.Header.Set(idempotencyKeyHeader, "abc")
+			req.Header.Set("Authorization", "Bearer "+token)
+			server.ServeHTTP(rec, req)
+			return rec.Code
+		}
+		postAs(token("alice", time.Now().Add(time.Hour)))
+		postAs(token("alice", time.Now().Add(2*time.Hour)))
+		if n := lifts(t, svc); n != 1 {
+			t.Errorf("expected a refreshed token to replay the same client's response, got %d lifts", n)
+		}
+		postAs(token("bob", time.Now().Add(time.Hour)))
+		if n := lifts(t, svc); n != 2 {
+			t.Errorf("expected another client's request to be handled, got %d lifts", n)
+		}
+		if code := postAs("not-a-token"); code != 401 {
+			t.Errorf("expected 401, got %d", code)
+		}
+	})
+
+	t.Run("concurrent retries are handled once", func(t *testing.T) {
+		var handled atomic.Int32
+		release := make(chan struct{})
+		server := idempotent(newIdempotencyStore(time.Hour), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled.Add(1)
+			<-release
+			w.WriteHeader(201)
+		}))
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if rec := post(server, "/lift", `{}`, "abc", ""); rec.Code != 201 {
+					t.Errorf("expected 201, got %d", rec.Code)
+				}
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if n := handled.Load(); n != 1 {
+			t.Errorf("expected the request to be handled once, got %d", n)
+		}
+	})
+
+	t.Run("responses expire, and server errors aren't kept", func(t *testing.T) {
+		var handled atomic.Int32
+		status := 500
+		store := newIdempotencyStore(time.Hour)
+		now := time.Now()
+		store.now = func() time.Time { return now }
+		server := idempotent(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handled.Add(1)
+			w.WriteHeader(status)
+		}))
+		post(server, "/lift", `{}`, "abc", "")
+		status = 201
+		post(server, "/lift", `{}`, "abc", "")
+		post(server, "/lift", `{}`, "abc", "")
+		if n := handled.Load(); n != 2 {
+			t.Errorf("expected the failed request to be retried once, got %d", n)
+		}
+		now = now.Add(time.Hour)
+		post(server, "/lift", `{}`, "abc", "")
+		if n := handled.Load(); n != 3 {
+			t.Errorf("expected the key to have expired, got %d", n)
+		}
+	})
+}
@@ -1,38 +1,70 @@
 package httpadapter
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/recording"
 )
 
-type errorResponse struct {
-	Code  int     `json:"code"`
-	Error *string `json:"error"`
-}
-
 func okResponse[T any](w http.ResponseWriter, status int, v T) {
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(v)
 }
 
-func errResponse(w http.ResponseWriter, status int, err error) {
-	w.Header().Add("Content-Type", "application/json")
-	w.WriteHeader(status)
-	errMessage := err.Error()
-	json.NewEncoder(w).Encode(errorResponse{
-		Code:  status,
-		Error: &errMessage,
-	})
+type floorRangeReq struct {
+	From int `json:"from"`
+	To   int `json:"to"`
 }
 
 type createLiftReq struct {
-	Floor        int `json:"floor"`
-	FloorDelayMs int `json:"floor_delay_ms"`
+	Floor        int             `json:"floor"`
+	FloorDelayMs int             `json:"floor_delay_ms"`
+	DoorDwellMs  int             `json:"door_dwell_ms"`
+	ServedFloors []floorRangeReq `json:"served_floors"`
+	Decks        int             `json:"decks"`
+	Shaft        string          `json:"shaft"`
+}
+
+func (req createLiftReq) validate(v *validator) {
+	v.floor("floor", req.Floor)
+	v.nonNegative("floor_delay_ms", req.FloorDelayMs)
+	v.nonNegative("door_dwell_ms", req.DoorDwellMs)
+	for i, served := range req.ServedFloors {
+		field := fmt.Sprintf("served_floors[%d]", i)
+		v.floor(field+".from", served.From)
+		v.floor(field+".to", served.To)
+		if served.From > served.To {
+			v.add(field, CodeInvalidFloorRange, "from must not be above to")
+		}
+	}
+	if req.Decks < 0 || req.Decks > 2 {
+		v.add("decks", CodeInvalidDecks, "must be 1 or 2")
+	}
+}
+
+func servedFloors(ranges []floorRangeReq) []lift.FloorRange {
+	var floors []lift.FloorRange
+	for _, r := range ranges {
+		floors = append(floors, lift.FloorRange{From: r.From, To: r.To})
+	}
+	return floors
+}
+
+func newFloorRangeRes(floors []lift.FloorRange) []floorRangeReq {
+	var ranges []floorRangeReq
+	for _, r := range floors {
+		ranges = append(ranges, floorRangeReq{From: r.From, To: r.To})
+	}
+	return ranges
 }
 
 type createLiftRes struct {
@@ -40,20 +72,43 @@ type createLiftRes struct {
 	Floor int         `json:"floor"`
 }
 
-func createLiftHandler(svc *lift.LiftService) http.Handler {
+// addLift adds the lift described by body and records it.
+func addLift(ctx context.Context, svc *lift.LiftService, rec *recording.Recorder, body createLiftReq) (lift.Lift, error) {
+	l, err := svc.AddLift(ctx, lift.LiftConfig{
+		Floor:        body.Floor,
+		FloorDelayMs: body.FloorDelayMs,
+		DoorDwellMs:  body.DoorDwellMs,
+		ServedFloors: servedFloors(body.ServedFloors),
+		Decks:        body.Decks,
+		Shaft:        body.Shaft,
+	})
+	if err != nil {
+		return l, err
+	}
+	rec.RecordCommand(recording.Command{
+		Type:         recording.CommandAddLift,
+		LiftId:       l.Id,
+		Floor:        body.Floor,
+		FloorDelayMs: body.FloorDelayMs,
+		DoorDwellMs:  body.DoorDwellMs,
+		ServedFloors: l.ServedFloors,
+		Decks:        body.Decks,
+		Shaft:        body.Shaft,
+	})
+	return l, nil
+}
+
+func createLiftHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var body createLiftReq
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
-		err := decoder.Decode(&body)
-		if err != nil && err != io.EOF {
-			errResponse(w, 400, err)
+		body, err := decodeBody[createLiftReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
 
-		lift, err := svc.AddLift(r.Context(), lift.LiftConfig{Floor: body.Floor, FloorDelayMs: body.FloorDelayMs})
+		lift, err := addLift(r.Context(), svc, rec, body)
 		if err != nil {
-			errResponse(w, 500, err)
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
 
@@ -62,41 +117,162 @@ func createLiftHandler(svc *lift.LiftService) http.Handler {
 }
 
 type callLiftReq struct {
-	Floor int `json:"floor"`
+	Floor *int `json:"floor"`
+}
+
+func (req callLiftReq) validate(v *validator) {
+	if v.required("floor", req.Floor != nil) {
+		v.floor("floor", *req.Floor)
+	}
+}
+
+type callRes struct {
+	Id          lift.CallId `json:"id"`
+	LiftId      lift.LiftId `json:"lift_id"`
+	Floor       int         `json:"floor"`
+	RequestedAt time.Time   `json:"requested_at"`
+	ArrivedAt   *time.Time  `json:"arrived_at,omitempty"`
+	WaitMs      *int64      `json:"wait_ms,omitempty"`
+	Deck        lift.Deck   `json:"deck,omitempty"`
+	Legs        []legRes    `json:"legs,omitempty"`
+}
+
+type legRes struct {
+	LiftId lift.LiftId `json:"lift_id"`
+	From   int         `json:"from"`
+	To     int         `json:"to"`
+}
+
+func newLegsRes(legs []lift.Leg) []legRes {
+	var res []legRes
+	for _, leg := range legs {
+		res = append(res, legRes{LiftId: leg.LiftId, From: leg.From, To: leg.To})
+	}
+	return res
+}
+
+func newCallRes(call lift.Call) callRes {
+	res := callRes{Id: call.Id, LiftId: call.LiftId, Floor: call.Floor, RequestedAt: call.RequestedAt, Deck: call.Deck}
+	if !call.ArrivedAt.IsZero() {
+		waitMs := call.WaitTime().Milliseconds()
+		res.ArrivedAt = &call.ArrivedAt
+		res.WaitMs = &waitMs
+	}
+	return res
+}
+
+const (
+	defaultCallWaitTimeout = 30 * time.Second
+	maxCallWaitTimeout     = 5 * time.Minute
+)
+
+var errCallWaitTimedOut = errors.New("timed out waiting for the lift to arrive")
+
+// callWaitTimeout reads the ?wait=true&timeout=10s query parameters used to
+// hold a call response until the lift arrives.
+func callWaitTimeout(r *http.Request) (time.Duration, bool, error) {
+	query := r.URL.Query()
+	if wait, _ := strconv.ParseBool(query.Get("wait")); !wait {
+		return 0, false, nil
+	}
+	if query.Get("timeout") == "" {
+		return defaultCallWaitTimeout, true, nil
+	}
+	timeout, err := time.ParseDuration(query.Get("timeout"))
+	if err != nil || timeout <= 0 || timeout > maxCallWaitTimeout {
+		return 0, false, validationError{{Field: "timeout", Code: CodeInvalidValue, Message: "must be a positive duration of at most 5m"}}
+	}
+	return timeout, true, nil
 }
 
-func callLiftHandler(svc *lift.LiftService) http.Handler {
+// callResponse responds with the call, along with the legs of the journey
+// it starts if the call was dispatched with a destination.
+func callResponse(w http.ResponseWriter, r *http.Request, handle *lift.CallHandle, timeout time.Duration, wait bool, legs []lift.Leg) {
+	withLegs := func(res callRes) callRes {
+		res.Legs = newLegsRes(legs)
+		return res
+	}
+	if !wait {
+		okResponse(w, 201, withLegs(newCallRes(handle.Call())))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	call, err := handle.Wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		errResponse(w, 504, errCallWaitTimedOut)
+		return
+	}
+	if err != nil {
+		errResponse(w, liftErrStatus(err), err)
+		return
+	}
+	okResponse(w, 200, withLegs(newCallRes(call)))
+}
+
+func callLiftHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var body createLiftReq
-		decoder := json.NewDecoder(r.Body)
-		decoder.DisallowUnknownFields()
-		err := decoder.Decode(&body)
-		if err != nil && err != io.EOF {
-			errResponse(w, 400, err)
+		body, err := decodeBody[callLiftReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
 
-		id, err := lift.ParseLiftId(r.PathValue("id"))
+		id, err := liftIdParam(r)
 		if err != nil {
-			errResponse(w, 404, lift.ErrLiftNotFound)
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
 
-		if err = svc.CallLift(r.Context(), id, body.Floor); err != nil {
-			if errors.Is(err, lift.ErrLiftNotFound) {
-				errResponse(w, 404, lift.ErrLiftNotFound)
-				return
-			}
-			errResponse(w, 500, err)
+		timeout, wait, err := callWaitTimeout(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
-		okResponse(w, 201, struct{}{})
+
+		handle, err := svc.CallLift(r.Context(), id, *body.Floor)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		rec.RecordCommand(recording.Command{Type: recording.CommandCallLift, LiftId: id, Floor: *body.Floor})
+		callResponse(w, r, handle, timeout, wait, nil)
 	})
 }
 
 type getLiftRes struct {
-	Id    lift.LiftId `json:"id"`
-	Floor int         `json:"floor"`
+	Id           lift.LiftId      `json:"id"`
+	Floor        int              `json:"floor"`
+	Status       lift.LiftStatus  `json:"status"`
+	Retiring     bool             `json:"retiring,omitempty"`
+	DoorsOpen    bool             `json:"doors_open"`
+	Faults       []lift.FaultType `json:"faults"`
+	EnergyWh     float64          `json:"energy_wh"`
+	ServedFloors []floorRangeReq  `json:"served_floors,omitempty"`
+	Decks        int              `json:"decks"`
+	Shaft        string           `json:"shaft,omitempty"`
+	Car          lift.Car         `json:"car,omitempty"`
+}
+
+func newGetLiftRes(l lift.Lift) getLiftRes {
+	faults := l.Faults
+	if faults == nil {
+		faults = []lift.FaultType{}
+	}
+	return getLiftRes{
+		Id:           l.Id,
+		Floor:        l.Floor,
+		Status:       l.Status,
+		Retiring:     l.Retiring,
+		DoorsOpen:    l.DoorsOpen,
+		Faults:       faults,
+		EnergyWh:     l.EnergyWh,
+		ServedFloors: newFloorRangeRes(l.ServedFloors),
+		Decks:        l.Decks,
+		Shaft:        l.Shaft,
+		Car:          l.Car,
+	}
 }
 
 func getLiftsHandler(svc *lift.LiftService) http.Handler {
@@ -106,10 +282,7 @@ func getLiftsHandler(svc *lift.LiftService) http.Handler {
 		} else {
 			body := make([]getLiftRes, len(lifts))
 			for i, lift := range lifts {
-				body[i] = getLiftRes{
-					Id:    lift.Id,
-					Floor: lift.Floor,
-				}
+				body[i] = newGetLiftRes(lift)
 			}
 			okResponse(w, 200, body)
 		}
@@ -118,27 +291,513 @@ func getLiftsHandler(svc *lift.LiftService) http.Handler {
 
 func getLiftHandler(svc *lift.LiftService) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		id, err := lift.ParseLiftId(r.PathValue("id"))
+		id, err := liftIdParam(r)
 		if err != nil {
-			errResponse(w, 404, lift.ErrLiftNotFound)
+			errResponse(w, liftErrStatus(err), err)
 			return
 		}
 		if l, err := svc.GetLift(r.Context(), id); err != nil {
-			statusCode := 500
-			if errors.Is(err, lift.ErrLiftNotFound) {
-				statusCode = 404
-			}
-			errResponse(w, statusCode, err)
+			errResponse(w, liftErrStatus(err), err)
 		} else {
-			okResponse(w, 200, getLiftRes{Id: l.Id, Floor: l.Floor})
+			okResponse(w, 200, newGetLiftRes(l))
 		}
 	})
 }
 
-func NewController(mux *http.ServeMux, svc *lift.LiftService) *http.ServeMux {
-	mux.Handle("POST /lift", createLiftHandler(svc))
-	mux.Handle("GET /lift", getLiftsHandler(svc))
-	mux.Handle("GET /lift/{id}", getLiftHandler(svc))
-	mux.Handle("POST /lift/{id}/call", callLiftHandler(svc))
+type maintenanceReq struct {
+	AbandonTrip bool `json:"abandon_trip"`
+}
+
+func (maintenanceReq) validate(*validator) {}
+
+func startMaintenanceHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[maintenanceReq](w, r, defaultFloorLimits)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		mode := lift.FinishTrip
+		if body.AbandonTrip {
+			mode = lift.AbandonTrip
+		}
+		if l, err := svc.TakeOutOfService(r.Context(), id, mode); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{Type: recording.CommandStartMaintenance, LiftId: id, Mode: mode})
+			okResponse(w, 200, newGetLiftRes(l))
+		}
+	})
+}
+
+func endMaintenanceHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		if l, err := svc.ReturnToService(r.Context(), id); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{Type: recording.CommandEndMaintenance, LiftId: id})
+			okResponse(w, 200, newGetLiftRes(l))
+		}
+	})
+}
+
+type dispatchReq struct {
+	Floor *int `json:"floor"`
+	// Destination is optional. With it, only lifts serving both floors are
+	// dispatched, and the response lists any changes of lift on the way.
+	Destination *int `json:"destination"`
+	// Credential lets the passenger travel to a restricted destination.
+	Credential string `json:"credential"`
+}
+
+func (req dispatchReq) validate(v *validator) {
+	if v.required("floor", req.Floor != nil) {
+		v.floor("floor", *req.Floor)
+	}
+	if req.Destination == nil {
+		return
+	}
+	v.floor("destination", *req.Destination)
+	if req.Floor != nil && *req.Destination == *req.Floor {
+		v.add("destination", CodeInvalidValue, "must differ from floor")
+	}
+}
+
+// dispatch sends the best lift to floor, or the best lift serving both floor
+// and destination if there is one, and records the call.
+func dispatch(ctx context.Context, svc *lift.LiftService, rec *recording.Recorder, floor int, destination *int, credential string) (*lift.CallHandle, []lift.Leg, error) {
+	var handle *lift.CallHandle
+	var legs []lift.Leg
+	var err error
+	if destination != nil {
+		handle, legs, err = svc.DispatchTo(lift.WithCredential(ctx, credential), floor, *destination)
+	} else {
+		handle, err = svc.Dispatch(ctx, floor)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	rec.RecordCommand(recording.Command{Type: recording.CommandDispatch, Floor: floor, Destination: destination})
+	return handle, legs, nil
+}
+
+func dispatchHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[dispatchReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		timeout, wait, err := callWaitTimeout(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		handle, legs, err := dispatch(r.Context(), svc, rec, *body.Floor, body.Destination, body.Credential)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		callResponse(w, r, handle, timeout, wait, legs)
+	})
+}
+
+type emergencyRecallReq struct {
+	Floor *int `json:"floor"`
+}
+
+func (req emergencyRecallReq) validate(v *validator) {
+	if v.required("floor", req.Floor != nil) {
+		v.floor("floor", *req.Floor)
+	}
+}
+
+type emergencyRecallRes struct {
+	Active bool `json:"active"`
+	Floor  *int `json:"floor"`
+}
+
+func newEmergencyRecallRes(svc *lift.LiftService) emergencyRecallRes {
+	floor, active := svc.EmergencyRecall()
+	if !active {
+		return emergencyRecallRes{}
+	}
+	return emergencyRecallRes{Active: true, Floor: &floor}
+}
+
+func getEmergencyRecallHandler(svc *lift.LiftService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okResponse(w, 200, newEmergencyRecallRes(svc))
+	})
+}
+
+func startEmergencyRecallHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[emergencyRecallReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		if err := svc.StartEmergencyRecall(r.Context(), *body.Floor); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		rec.RecordCommand(recording.Command{Type: recording.CommandStartEmergencyRecall, Floor: *body.Floor})
+		okResponse(w, 201, newEmergencyRecallRes(svc))
+	})
+}
+
+func clearEmergencyRecallHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := svc.ClearEmergencyRecall(r.Context()); err != nil {
+			errResponse(w, 500, err)
+			return
+		}
+		rec.RecordCommand(recording.Command{Type: recording.CommandClearEmergencyRecall})
+		okResponse(w, 200, newEmergencyRecallRes(svc))
+	})
+}
+
+func startIndependentServiceHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		if l, err := svc.StartIndependentService(r.Context(), id); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{Type: recording.CommandStartIndependentService, LiftId: id})
+			okResponse(w, 200, newGetLiftRes(l))
+		}
+	})
+}
+
+func endIndependentServiceHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		if l, err := svc.EndIndependentService(r.Context(), id); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{Type: recording.CommandEndIndependentService, LiftId: id})
+			okResponse(w, 200, newGetLiftRes(l))
+		}
+	})
+}
+
+type carCallReq struct {
+	Floor *int `json:"floor"`
+	// Credential lets the passenger travel to a restricted floor.
+	Credential string `json:"credential"`
+}
+
+func (req carCallReq) validate(v *validator) {
+	if v.required("floor", req.Floor != nil) {
+		v.floor("floor", *req.Floor)
+	}
+}
+
+func carCallHandler(svc *lift.LiftService, rec *recording.Recorder, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[carCallReq](w, r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		handle, err := svc.CarCall(lift.WithCredential(r.Context(), body.Credential), id, *body.Floor)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		rec.RecordCommand(recording.Command{Type: recording.CommandCarCall, LiftId: id, Floor: *body.Floor})
+		okResponse(w, 201, newCallRes(handle.Call()))
+	})
+}
+
+type injectFaultReq struct {
+	Type           lift.FaultType `json:"type"`
+	SlowdownFactor float64        `json:"slowdown_factor"`
+	DropRate       float64        `json:"drop_rate"`
+}
+
+func (req injectFaultReq) validate(v *validator) {
+	v.required("type", req.Type != "")
+	if req.SlowdownFactor != 0 && req.SlowdownFactor <= 1 {
+		v.add("slowdown_factor", CodeInvalidValue, "must be greater than 1")
+	}
+	if req.DropRate < 0 || req.DropRate > 1 {
+		v.add("drop_rate", CodeInvalidValue, "must be between 0 and 1")
+	}
+}
+
+func injectFaultHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := decodeBody[injectFaultReq](w, r, defaultFloorLimits)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		fault := lift.Fault{Type: body.Type, SlowdownFactor: body.SlowdownFactor, DropRate: body.DropRate}
+		if l, err := svc.InjectFault(r.Context(), id, fault); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{
+				Type:           recording.CommandInjectFault,
+				LiftId:         id,
+				Fault:          fault.Type,
+				SlowdownFactor: fault.SlowdownFactor,
+				DropRate:       fault.DropRate,
+			})
+			okResponse(w, 201, newGetLiftRes(l))
+		}
+	})
+}
+
+func clearFaultHandler(svc *lift.LiftService, rec *recording.Recorder) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		fault := lift.FaultType(r.PathValue("fault"))
+		if l, err := svc.ClearFault(r.Context(), id, fault); err != nil {
+			errResponse(w, liftErrStatus(err), err)
+		} else {
+			rec.RecordCommand(recording.Command{Type: recording.CommandClearFault, LiftId: id, Fault: fault})
+			okResponse(w, 200, newGetLiftRes(l))
+		}
+	})
+}
+
+type waitStatsRes struct {
+	Count     int   `json:"count"`
+	AverageMs int64 `json:"average_wait_ms"`
+	P50Ms     int64 `json:"p50_wait_ms"`
+	P95Ms     int64 `json:"p95_wait_ms"`
+	MaxMs     int64 `json:"max_wait_ms"`
+}
+
+func newWaitStatsRes(s lift.WaitStats) waitStatsRes {
+	return waitStatsRes{
+		Count:     s.Count,
+		AverageMs: s.Average.Milliseconds(),
+		P50Ms:     s.P50.Milliseconds(),
+		P95Ms:     s.P95.Milliseconds(),
+		MaxMs:     s.Max.Milliseconds(),
+	}
+}
+
+type liftStatsRes struct {
+	LiftId lift.LiftId `json:"lift_id"`
+	waitStatsRes
+}
+
+type floorStatsRes struct {
+	Floor int `json:"floor"`
+	waitStatsRes
+}
+
+type liftEnergyRes struct {
+	LiftId   lift.LiftId `json:"lift_id"`
+	EnergyWh float64     `json:"energy_wh"`
+}
+
+type energyStatsRes struct {
+	TotalWh float64         `json:"total_wh"`
+	Lifts   []liftEnergyRes `json:"lifts"`
+}
+
+type statsRes struct {
+	WindowMs int64           `json:"window_ms"`
+	Overall  waitStatsRes    `json:"overall"`
+	Lifts    []liftStatsRes  `json:"lifts"`
+	Floors   []floorStatsRes `json:"floors"`
+	Energy   energyStatsRes  `json:"energy"`
+}
+
+func getStatsHandler(svc *lift.LiftService) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats, err := svc.Stats(r.Context())
+		if err != nil {
+			errResponse(w, 500, err)
+			return
+		}
+
+		body := statsRes{
+			WindowMs: stats.Window.Milliseconds(),
+			Overall:  newWaitStatsRes(stats.Overall),
+			Lifts:    []liftStatsRes{},
+			Floors:   []floorStatsRes{},
+			Energy:   energyStatsRes{TotalWh: stats.TotalEnergyWh, Lifts: []liftEnergyRes{}},
+		}
+		for id, s := range stats.ByLift {
+			body.Lifts = append(body.Lifts, liftStatsRes{LiftId: id, waitStatsRes: newWaitStatsRes(s)})
+		}
+		for floor, s := range stats.ByFloor {
+			body.Floors = append(body.Floors, floorStatsRes{Floor: floor, waitStatsRes: newWaitStatsRes(s)})
+		}
+		for id, wh := range stats.EnergyWh {
+			body.Energy.Lifts = append(body.Energy.Lifts, liftEnergyRes{LiftId: id, EnergyWh: wh})
+		}
+		sort.Slice(body.Lifts, func(i, j int) bool { return body.Lifts[i].LiftId.String() < body.Lifts[j].LiftId.String() })
+		sort.Slice(body.Energy.Lifts, func(i, j int) bool {
+			return body.Energy.Lifts[i].LiftId.String() < body.Energy.Lifts[j].LiftId.String()
+		})
+		sort.Slice(body.Floors, func(i, j int) bool { return body.Floors[i].Floor < body.Floors[j].Floor })
+		okResponse(w, 200, body)
+	})
+}
+
+type etaRes struct {
+	LiftId lift.LiftId `json:"lift_id"`
+	Floor  int         `json:"floor"`
+	EtaMs  int64       `json:"eta_ms"`
+}
+
+func getEtaHandler(svc *lift.LiftService, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := liftIdParam(r)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		floor, err := floorQuery(r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		eta, err := svc.EstimateArrival(r.Context(), id, floor)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		okResponse(w, 200, etaRes{LiftId: id, Floor: floor, EtaMs: eta.Milliseconds()})
+	})
+}
+
+func getBestEtaHandler(svc *lift.LiftService, floors floorLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		floor, err := floorQuery(r, floors)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		id, eta, err := svc.EstimateBestArrival(r.Context(), floor)
+		if err != nil {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+		okResponse(w, 200, etaRes{LiftId: id, Floor: floor, EtaMs: eta.Milliseconds()})
+	})
+}
+
+type controllerOptions struct {
+	recorder       *recording.Recorder
+	floors         floorLimits
+	idempotencyTTL time.Duration
+	reload         ReloadFunc
+}
+
+type Option func(opts *controllerOptions)
+
+// WithRecorder records every command sent through the controller that
+// changes the lifts.
+func WithRecorder(rec *recording.Recorder) Option {
+	return func(opts *controllerOptions) {
+		opts.recorder = rec
+	}
+}
+
+// WithFloors limits the floors requests may refer to.
+func WithFloors(lowest, highest int) Option {
+	return func(opts *controllerOptions) {
+		opts.floors = floorLimits{lowest: lowest, highest: highest}
+	}
+}
+
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+func controllerRoutes(svc *lift.LiftService, o controllerOptions) []route {
+	store := newIdempotencyStore(o.idempotencyTTL)
+	routes := []route{
+		{"POST /lift", idempotent(store, createLiftHandler(svc, o.recorder, o.floors))},
+		{"POST /lift/batch", idempotent(store, createLiftBatchHandler(svc, o.recorder, o.floors))},
+		{"GET /lift", getLiftsHandler(svc)},
+		{"GET /lift/{id}", getLiftHandler(svc)},
+		{"GET /lift/{id}/eta", getEtaHandler(svc, o.floors)},
+		{"POST /lift/{id}/call", idempotent(store, callLiftHandler(svc, o.recorder, o.floors))},
+		{"POST /lift/{id}/maintenance", startMaintenanceHandler(svc, o.recorder)},
+		{"DELETE /lift/{id}/maintenance", endMaintenanceHandler(svc, o.recorder)},
+		{"POST /lift/{id}/car-call", carCallHandler(svc, o.recorder, o.floors)},
+		{"POST /lift/{id}/independent", startIndependentServiceHandler(svc, o.recorder)},
+		{"DELETE /lift/{id}/independent", endIndependentServiceHandler(svc, o.recorder)},
+		{"POST /call", dispatchHandler(svc, o.recorder, o.floors)},
+		{"POST /calls/batch", idempotent(store, callBatchHandler(svc, o.recorder, o.floors))},
+		{"GET /eta", getBestEtaHandler(svc, o.floors)},
+		{"GET /stats", getStatsHandler(svc)},
+		{"GET /admin/emergency-recall", getEmergencyRecallHandler(svc)},
+		{"POST /admin/emergency-recall", startEmergencyRecallHandler(svc, o.recorder, o.floors)},
+		{"DELETE /admin/emergency-recall", clearEmergencyRecallHandler(svc, o.recorder)},
+		{"POST /admin/lift/{id}/faults", injectFaultHandler(svc, o.recorder)},
+		{"DELETE /admin/lift/{id}/faults/{fault}", clearFaultHandler(svc, o.recorder)},
+	}
+	if o.reload != nil {
+		routes = append(routes, route{"POST /admin/reload", reloadHandler(o.reload)})
+	}
+	return routes
+}
+
+func NewController(mux *http.ServeMux, svc *lift.LiftService, opts ...Option) *http.ServeMux {
+	o := controllerOptions{floors: defaultFloorLimits, idempotencyTTL: defaultIdempotencyTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, r := range controllerRoutes(svc, o) {
+		mux.Handle(r.pattern, r.handler)
+	}
 	return mux
 }
@@ -9,12 +9,14 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/leow93/miffed-api/internal/lift"
 	"github.com/leow93/miffed-api/internal/pubsub"
+	"github.com/leow93/miffed-api/internal/recording"
 )
 
 func waitFor[T any](f func() (T, error), timer <-chan time.Time) (T, error) {
@@ -124,7 +126,7 @@ func Test_LiftController(t *testing.T) {
 
 	t.Run("GET /lift/{id} returns 404 for unknown lift", func(t *testing.T) {
 		rec := httptest.NewRecorder()
-		req := httptest.NewRequest("GET", "/lift/123", io.Reader(strings.NewReader("")))
+		req := httptest.NewRequest("GET", "/lift/"+lift.NewLiftId().String(), io.Reader(strings.NewReader("")))
 		server.ServeHTTP(rec, req)
 
 		result := rec.Result()
@@ -238,6 +240,387 @@ func Test_LiftController(t *testing.T) {
 	})
 }
 
+func Test_Maintenance(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+	t.Run("POST /lift/{id}/maintenance takes the lift out of service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"abandon_trip\": true}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/maintenance", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.Status != lift.StatusOutOfService {
+			t.Errorf("expected %s, got %s", lift.StatusOutOfService, res.Status)
+		}
+	})
+
+	t.Run("POST /lift/{id}/call returns 409 while out of service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/call", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 409 {
+			t.Errorf("expected 409, got %d", result.StatusCode)
+		}
+	})
+
+	t.Run("POST /call returns 503 when no lift is in service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/call", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 503 {
+			t.Errorf("expected 503, got %d", result.StatusCode)
+		}
+	})
+
+	t.Run("DELETE /lift/{id}/maintenance returns the lift to service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/lift/"+l.Id.String()+"/maintenance", nil)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.Status != lift.StatusInService {
+			t.Errorf("expected %s, got %s", lift.StatusInService, res.Status)
+		}
+	})
+
+	t.Run("POST /call dispatches a lift", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/call", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 201 {
+			t.Errorf("expected 201, got %d", result.StatusCode)
+		}
+		res := callRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.LiftId != l.Id {
+			t.Errorf("expected %s, got %s", l.Id, res.LiftId)
+		}
+	})
+}
+
+func Test_EmergencyRecall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 3})
+
+	t.Run("POST /admin/emergency-recall starts a recall", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 0}"))
+		req := httptest.NewRequest("POST", "/admin/emergency-recall", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 201 {
+			t.Errorf("expected 201, got %d", result.StatusCode)
+		}
+		res := emergencyRecallRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if !res.Active || res.Floor == nil || *res.Floor != 0 {
+			t.Errorf("expected an active recall to floor 0, got %+v", res)
+		}
+		if _, err := waitForLiftAtFloor(svc, l.Id, 0); err != nil {
+			t.Errorf("expected no error, got %e", err)
+		}
+	})
+
+	t.Run("POST /admin/emergency-recall returns 409 when already active", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 1}"))
+		req := httptest.NewRequest("POST", "/admin/emergency-recall", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 409 {
+			t.Errorf("expected 409, got %d", rec.Result().StatusCode)
+		}
+	})
+
+	t.Run("POST /lift/{id}/call returns 409 during a recall", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/call", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 409 {
+			t.Errorf("expected 409, got %d", rec.Result().StatusCode)
+		}
+	})
+
+	t.Run("DELETE /admin/emergency-recall clears the recall", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/admin/emergency-recall", nil)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := emergencyRecallRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.Active {
+			t.Errorf("expected recall to be cleared, got %+v", res)
+		}
+	})
+}
+
+func Test_IndependentService(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+	t.Run("POST /lift/{id}/independent puts the lift in independent service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/independent", nil)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.Status != lift.StatusIndependent || !res.DoorsOpen {
+			t.Errorf("expected independent lift with open doors, got %+v", res)
+		}
+	})
+
+	t.Run("POST /lift/{id}/call returns 409 in independent service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/call", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 409 {
+			t.Errorf("expected 409, got %d", rec.Result().StatusCode)
+		}
+	})
+
+	t.Run("POST /lift/{id}/car-call moves the lift", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/car-call", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 201 {
+			t.Errorf("expected 201, got %d", rec.Result().StatusCode)
+		}
+		if _, err := waitForLiftAtFloor(svc, l.Id, 3); err != nil {
+			t.Errorf("expected no error, got %e", err)
+		}
+	})
+
+	t.Run("DELETE /lift/{id}/independent returns the lift to service", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/lift/"+l.Id.String()+"/independent", nil)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.Status != lift.StatusInService {
+			t.Errorf("expected %s, got %s", lift.StatusInService, res.Status)
+		}
+	})
+}
+
+func Test_Faults(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+	t.Run("POST /admin/lift/{id}/faults injects a fault", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"type\": \"slow_motor\", \"slowdown_factor\": 4}"))
+		req := httptest.NewRequest("POST", "/admin/lift/"+l.Id.String()+"/faults", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 201 {
+			t.Errorf("expected 201, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if len(res.Faults) != 1 || res.Faults[0] != lift.FaultSlowMotor {
+			t.Errorf("expected slow motor fault, got %v", res.Faults)
+		}
+	})
+
+	t.Run("POST /admin/lift/{id}/faults returns 400 for an unknown fault", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"type\": \"gremlins\"}"))
+		req := httptest.NewRequest("POST", "/admin/lift/"+l.Id.String()+"/faults", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 400 {
+			t.Errorf("expected 400, got %d", rec.Result().StatusCode)
+		}
+	})
+
+	t.Run("DELETE /admin/lift/{id}/faults/{fault} clears a fault", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/admin/lift/"+l.Id.String()+"/faults/slow_motor", nil)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := getLiftRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if len(res.Faults) != 0 {
+			t.Errorf("expected no faults, got %v", res.Faults)
+		}
+	})
+}
+
+func Test_Stats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+	t.Run("POST /lift/{id}/call returns the call", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 2}"))
+		req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/call", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 201 {
+			t.Errorf("expected 201, got %d", result.StatusCode)
+		}
+		res := callRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.LiftId != l.Id || res.Floor != 2 || res.RequestedAt.IsZero() {
+			t.Errorf("expected a call to floor 2, got %+v", res)
+		}
+	})
+
+	t.Run("GET /stats reports wait times per lift and floor, and energy use", func(t *testing.T) {
+		fn := func() (statsRes, error) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/stats", nil)
+			server.ServeHTTP(rec, req)
+			res := statsRes{}
+			json.NewDecoder(rec.Result().Body).Decode(&res)
+			if res.Overall.Count != 1 {
+				return res, errors.New("call not yet served")
+			}
+			return res, nil
+		}
+		res, err := waitFor(fn, time.After(time.Second))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(res.Lifts) != 1 || res.Lifts[0].LiftId != l.Id {
+			t.Errorf("expected stats for %s, got %+v", l.Id, res.Lifts)
+		}
+		if len(res.Floors) != 1 || res.Floors[0].Floor != 2 {
+			t.Errorf("expected stats for floor 2, got %+v", res.Floors)
+		}
+		// two floors and a stop under the default energy model
+		if res.Energy.TotalWh != 25 || len(res.Energy.Lifts) != 1 || res.Energy.Lifts[0].EnergyWh != 25 {
+			t.Errorf("expected 25Wh used, got %+v", res.Energy)
+		}
+	})
+}
+
+func Test_AwaitableCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := pubsub.NewMemoryPubSub()
+	svc := lift.NewLiftService(ctx, ps)
+	server := http.NewServeMux()
+	server = NewController(server, svc)
+	fast, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0, FloorDelayMs: 5})
+	slow, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0, FloorDelayMs: 1000})
+
+	t.Run("POST /lift/{id}/call?wait=true responds once the lift arrives", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+fast.Id.String()+"/call?wait=true&timeout=1s", body)
+		server.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		res := callRes{}
+		json.NewDecoder(result.Body).Decode(&res)
+		if res.ArrivedAt == nil || res.WaitMs == nil {
+			t.Fatalf("expected an arrival time, got %+v", res)
+		}
+		if *res.WaitMs < 15 {
+			t.Errorf("expected a wait of at least 15ms, got %d", *res.WaitMs)
+		}
+	})
+
+	t.Run("POST /lift/{id}/call?wait=true returns 504 when the lift is too slow", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+slow.Id.String()+"/call?wait=true&timeout=20ms", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 504 {
+			t.Errorf("expected 504, got %d", rec.Result().StatusCode)
+		}
+	})
+
+	t.Run("POST /lift/{id}/call returns 400 for an invalid timeout", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := io.Reader(strings.NewReader("{\"floor\": 3}"))
+		req := httptest.NewRequest("POST", "/lift/"+fast.Id.String()+"/call?wait=true&timeout=soon", body)
+		server.ServeHTTP(rec, req)
+
+		if rec.Result().StatusCode != 400 {
+			t.Errorf("expected 400, got %d", rec.Result().StatusCode)
+		}
+	})
+}
+
 func containsId(lifts []getLiftRes, id lift.LiftId) bool {
 	for _, l := range lifts {
 		if l.Id == id {
@@ -246,3 +629,267 @@ func containsId(lifts []getLiftRes, id lift.LiftId) bool {
 	}
 	return false
 }
+
+func Test_Recording(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	buf := bytes.Buffer{}
+	server := NewController(http.NewServeMux(), svc, WithRecorder(recording.NewRecorder(&buf)))
+
+	t.Run("adding and calling lifts is recorded", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift", createLiftBody(3)))
+		var created createLiftRes
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift/"+created.Id.String()+"/call", strings.NewReader(`{"floor":5}`)))
+		if rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+
+		entries, err := recording.Read(&buf)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		want := []recording.Command{
+			{Type: recording.CommandAddLift, LiftId: created.Id, Floor: 3},
+			{Type: recording.CommandCallLift, LiftId: created.Id, Floor: 5},
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+		}
+		for i, cmd := range want {
+			if entries[i].Command == nil || !reflect.DeepEqual(*entries[i].Command, cmd) {
+				t.Errorf("expected %+v, got %+v", cmd, entries[i].Command)
+			}
+		}
+	})
+
+	t.Run("dispatched calls are recorded, with or without a lift id in a batch", func(t *testing.T) {
+		l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+		requests := []struct{ path, body string }{
+			{"/call", `{"floor":4}`},
+			{"/call", `{"floor":0,"destination":6}`},
+			{"/calls/batch", `{"calls":[{"floor":2},{"lift_id":"` + l.Id.String() + `","floor":3}]}`},
+			{"/lift/" + l.Id.String() + "/car-call", `{"floor":1}`},
+			{"/lift/" + l.Id.String() + "/maintenance", `{"abandon_trip":true}`},
+		}
+		for _, req := range requests {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest("POST", req.path, strings.NewReader(req.body)))
+			if rec.Code >= 300 {
+				t.Fatalf("%s: expected success, got %d %s", req.path, rec.Code, rec.Body)
+			}
+		}
+
+		entries, err := recording.Read(&buf)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		six := 6
+		want := []recording.Command{
+			{Type: recording.CommandDispatch, Floor: 4},
+			{Type: recording.CommandDispatch, Floor: 0, Destination: &six},
+			{Type: recording.CommandDispatch, Floor: 2},
+			{Type: recording.CommandCallLift, LiftId: l.Id, Floor: 3},
+			{Type: recording.CommandCarCall, LiftId: l.Id, Floor: 1},
+			{Type: recording.CommandStartMaintenance, LiftId: l.Id, Mode: lift.AbandonTrip},
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("expected %d entries, got %d", len(want), len(entries))
+		}
+		for i, cmd := range want {
+			if entries[i].Command == nil || !reflect.DeepEqual(*entries[i].Command, cmd) {
+				t.Errorf("expected %+v, got %+v", cmd, entries[i].Command)
+			}
+		}
+	})
+}
+
+func Test_Eta(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	server := NewController(http.NewServeMux(), svc)
+	near, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 2, FloorDelayMs: 100})
+	svc.AddLift(ctx, lift.LiftConfig{Floor: 10, FloorDelayMs: 100})
+
+	t.Run("estimates when a lift would arrive", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/lift/"+near.Id.String()+"/eta?floor=5", nil))
+		if rec.Code != 200 {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var res etaRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		if res.LiftId != near.Id || res.Floor != 5 || res.EtaMs != 300 {
+			t.Errorf("expected lift %s at 5 in 300ms, got %+v", near.Id, res)
+		}
+	})
+
+	t.Run("estimates which lift would arrive first", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/eta?floor=4", nil))
+		if rec.Code != 200 {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var res etaRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		if res.LiftId != near.Id || res.EtaMs != 200 {
+			t.Errorf("expected lift %s in 200ms, got %+v", near.Id, res)
+		}
+	})
+
+	t.Run("the floor is required", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/eta?floor=top", nil))
+		if rec.Code != 400 {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("unknown lifts are not found", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/lift/"+lift.NewLiftId().String()+"/eta?floor=1", nil))
+		if rec.Code != 404 {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+}
+
+func Test_Zoning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	server := NewController(http.NewServeMux(), svc)
+	low, _ := svc.AddLift(ctx, lift.LiftConfig{ServedFloors: []lift.FloorRange{{From: 0, To: 10}}})
+
+	t.Run("POST /lift accepts served floors", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"floor": 10, "served_floors": [{"from": 10, "to": 20}]}`)
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift", body))
+		if rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+		var created createLiftRes
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/lift/"+created.Id.String(), nil))
+		var res getLiftRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		if len(res.ServedFloors) != 1 || res.ServedFloors[0] != (floorRangeReq{From: 10, To: 20}) {
+			t.Errorf("expected the lift to serve 10-20, got %+v", res.ServedFloors)
+		}
+	})
+
+	t.Run("POST /lift rejects invalid ranges", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"served_floors": [{"from": 5, "to": 1}]}`)
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift", body))
+		if rec.Code != 400 {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("calling a lift to an unserved floor is a bad request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift/"+low.Id.String()+"/call", strings.NewReader(`{"floor": 15}`)))
+		if rec.Code != 400 {
+			t.Errorf("expected 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("POST /call with a destination plans the journey", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/call", strings.NewReader(`{"floor": 2, "destination": 15}`)))
+		if rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+		var res callRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		if res.LiftId != low.Id || len(res.Legs) != 2 || res.Legs[0].To != 10 || res.Legs[1].To != 15 {
+			t.Errorf("expected to change lifts at floor 10, got %+v", res)
+		}
+	})
+}
+
+func Test_DecksAndShafts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	server := NewController(http.NewServeMux(), svc)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift", strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("POST /lift adds a double-deck lift", func(t *testing.T) {
+		rec := post(`{"floor": 0, "decks": 2}`)
+		if rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+		var created createLiftRes
+		json.NewDecoder(rec.Body).Decode(&created)
+		l, _ := svc.GetLift(ctx, created.Id)
+		if l.Decks != 2 {
+			t.Errorf("expected 2 decks, got %d", l.Decks)
+		}
+	})
+
+	t.Run("POST /lift adds cars to a shaft in order", func(t *testing.T) {
+		if rec := post(`{"floor": 0, "shaft": "a"}`); rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+		if rec := post(`{"floor": 0, "shaft": "a"}`); rec.Code != 409 {
+			t.Errorf("expected 409 for a car below the lower car, got %d", rec.Code)
+		}
+		rec := post(`{"floor": 4, "shaft": "a"}`)
+		if rec.Code != 201 {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+		var created createLiftRes
+		json.NewDecoder(rec.Body).Decode(&created)
+
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/lift/"+created.Id.String(), nil))
+		var res getLiftRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		if res.Shaft != "a" || res.Car != lift.CarUpper {
+			t.Errorf("expected the upper car of shaft a, got %+v", res)
+		}
+	})
+}
+
+func Test_AccessControl(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub(), lift.WithAccessRules(lift.AccessRule{Floor: 9, Credentials: []string{"badge-1"}}))
+	server := NewController(http.NewServeMux(), svc)
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"car calls to restricted floors are forbidden", "/lift/" + l.Id.String() + "/car-call", `{"floor": 9}`, 403},
+		{"car calls with a credential are let in", "/lift/" + l.Id.String() + "/car-call", `{"floor": 9, "credential": "badge-1"}`, 201},
+		{"destination dispatch is forbidden", "/call", `{"floor": 0, "destination": 9, "credential": "badge-2"}`, 403},
+		{"destination dispatch with a credential is let in", "/call", `{"floor": 0, "destination": 9, "credential": "badge-1"}`, 201},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest("POST", tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
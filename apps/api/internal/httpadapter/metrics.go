@@ -0,0 +1,22 @@
+package httpadapter
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/leow93/miffed-api/internal/metrics"
+)
+
+func metricsHandler(reg *metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := reg.Write(w); err != nil {
+			log.Println("error writing metrics", err)
+		}
+	})
+}
+
+func NewMetrics(mux *http.ServeMux, reg *metrics.Registry) *http.ServeMux {
+	mux.Handle("GET /metrics", metricsHandler(reg))
+	return mux
+}
@@ -0,0 +1,49 @@
+package httpadapter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/metrics"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Metrics(t *testing.T) {
+	t.Run("GET /metrics returns metrics in the Prometheus text format", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reg := metrics.NewRegistry()
+		ps := pubsub.NewMemoryPubSub(pubsub.WithMetrics(reg))
+		svc := lift.NewLiftService(ctx, ps, lift.WithMetrics(reg))
+		svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+
+		mux := http.NewServeMux()
+		mux = NewMetrics(mux, reg)
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		mux.ServeHTTP(rec, req)
+
+		result := rec.Result()
+		if result.StatusCode != 200 {
+			t.Errorf("expected 200, got %d", result.StatusCode)
+		}
+		if contentType := result.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+			t.Errorf("expected text/plain, got %s", contentType)
+		}
+		body, _ := io.ReadAll(result.Body)
+		for _, want := range []string{
+			"# TYPE miffed_lift_calls_received_total counter",
+			"# TYPE miffed_lift_pending_stops gauge",
+			"# TYPE miffed_pubsub_subscribers gauge",
+		} {
+			if !strings.Contains(string(body), want) {
+				t.Errorf("expected body to contain %q, got\n%s", want, body)
+			}
+		}
+	})
+}
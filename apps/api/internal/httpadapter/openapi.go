@@ -0,0 +1,25 @@
+package httpadapter
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes every route, request and response. It is written by
+// hand, and tests check it stays in step with the routes and types here.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func openAPIHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.Write(openAPISpec)
+	})
+}
+
+// NewOpenAPI serves the API's OpenAPI 3 description at GET /openapi.json.
+func NewOpenAPI(mux *http.ServeMux) *http.ServeMux {
+	mux.Handle("GET /openapi.json", openAPIHandler())
+	return mux
+}
@@ -0,0 +1,161 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/config"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Enum       []string                   `json:"enum"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadOpenAPI(t *testing.T) openAPIDoc {
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+// jsonFields are the names a struct's fields are encoded with.
+func jsonFields(typ reflect.Type) []string {
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			names = append(names, jsonFields(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name != "-" && name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Test_OpenAPI(t *testing.T) {
+	doc := loadOpenAPI(t)
+
+	t.Run("every route is described", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		patterns := []string{"GET /socket", "GET /metrics", "GET /openapi.json"}
+		reload := func(context.Context) ([]config.Change, error) { return nil, nil }
+		for _, r := range controllerRoutes(svc, controllerOptions{floors: defaultFloorLimits, reload: reload}) {
+			patterns = append(patterns, r.pattern)
+		}
+		for _, pattern := range patterns {
+			method, path, _ := strings.Cut(pattern, " ")
+			if _, ok := doc.Paths[path][strings.ToLower(method)]; !ok {
+				t.Errorf("%s is missing from openapi.json", pattern)
+			}
+		}
+	})
+
+	t.Run("schemas match the types they describe", func(t *testing.T) {
+		types := map[string]any{
+			"FloorRange":             floorRangeReq{},
+			"CreateLiftRequest":      createLiftReq{},
+			"CreatedLift":            createLiftRes{},
+			"Lift":                   getLiftRes{},
+			"CallLiftRequest":        callLiftReq{},
+			"CarCallRequest":         carCallReq{},
+			"DispatchRequest":        dispatchReq{},
+			"MaintenanceRequest":     maintenanceReq{},
+			"EmergencyRecallRequest": emergencyRecallReq{},
+			"InjectFaultRequest":     injectFaultReq{},
+			"CreateLiftsRequest":     createLiftBatchReq{},
+			"CallsRequest":           callBatchReq{},
+			"BatchCall":              batchCallReq{},
+			"LiftResult":             liftBatchItemRes{},
+			"LiftResults":            liftBatchRes{},
+			"CallResult":             callBatchItemRes{},
+			"CallResults":            callBatchRes{},
+			"ConfigChange":           config.Change{},
+			"ReloadResult":           reloadRes{},
+			"Call":                   callRes{},
+			"Leg":                    legRes{},
+			"Eta":                    etaRes{},
+			"EmergencyRecall":        emergencyRecallRes{},
+			"WaitStats":              waitStatsRes{},
+			"LiftStats":              liftStatsRes{},
+			"FloorStats":             floorStatsRes{},
+			"LiftEnergy":             liftEnergyRes{},
+			"EnergyStats":            energyStatsRes{},
+			"Stats":                  statsRes{},
+			"Error":                  errorResponse{},
+			"FieldError":             fieldError{},
+			"LiftEvent":              lift.LiftEvent{},
+		}
+		for name, v := range types {
+			schema, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Errorf("schema %s is missing", name)
+				continue
+			}
+			var properties []string
+			for property := range schema.Properties {
+				properties = append(properties, property)
+			}
+			sort.Strings(properties)
+			if want := jsonFields(reflect.TypeOf(v)); !reflect.DeepEqual(properties, want) {
+				t.Errorf("schema %s has properties %v, expected %v", name, properties, want)
+			}
+		}
+	})
+
+	t.Run("every error code is listed", func(t *testing.T) {
+		codes := map[ErrorCode]bool{CodeInternal: true}
+		for _, known := range knownErrors {
+			codes[known.code] = true
+		}
+		for _, code := range statusCodes {
+			codes[code] = true
+		}
+		for _, code := range []ErrorCode{CodeInvalidJSON, CodeUnknownField, CodeInvalidType, CodeMissingField, CodeInvalidValue, CodeFloorOutOfRange} {
+			codes[code] = true
+		}
+		enum := doc.Components.Schemas["ErrorCode"].Enum
+		for code := range codes {
+			found := false
+			for _, listed := range enum {
+				found = found || listed == string(code)
+			}
+			if !found {
+				t.Errorf("error code %s is missing from openapi.json", code)
+			}
+		}
+	})
+
+	t.Run("GET /openapi.json serves the document", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewOpenAPI(http.NewServeMux()).ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+		if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("expected 200 JSON, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+		}
+		var doc map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil || doc["openapi"] != "3.0.3" {
+			t.Errorf("expected an OpenAPI 3 document, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package httpadapter
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// RateLimit is a token bucket each client gets: it can make Burst requests at
+// once, and the bucket refills at Rate requests per second.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+type rateLimiter struct {
+	limit     RateLimit
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	mx        sync.Mutex
+}
+
+// NewRateLimit responds 429 Too Many Requests, with a Retry-After header, to
+// clients making requests faster than limit allows. Clients are told apart by
+// their IP address. A zero Rate means no limit. Use WithRateLimit to limit
+// authenticated clients by who they are instead.
+func NewRateLimit(next http.Handler, limit RateLimit) http.Handler {
+	rl := newRateLimiter(limit)
+	if rl == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.allow(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// allow spends a token for the client making r, responding 429 if it has
+// none. A nil rateLimiter allows everything.
+func (rl *rateLimiter) allow(w http.ResponseWriter, r *http.Request) bool {
+	if rl == nil {
+		return true
+	}
+	if wait, ok := rl.take(clientKey(r), time.Now()); !ok {
+		setRetryAfter(w, wait)
+		errResponse(w, 429, errRateLimited)
+		return false
+	}
+	return true
+}
+
+// take spends a token from client's bucket, or reports how long until one is
+// available.
+func (rl *rateLimiter) take(client string, now time.Time) (time.Duration, bool) {
+	rl.mx.Lock()
+	defer rl.mx.Unlock()
+	if now.Sub(rl.lastSweep) > time.Minute {
+		rl.sweep(now)
+	}
+	b, ok := rl.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(rl.limit.Burst), at: now}
+		rl.buckets[client] = b
+	}
+	b.tokens = rl.refilled(b, now)
+	b.at = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / rl.limit.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (rl *rateLimiter) refilled(b *bucket, now time.Time) float64 {
+	return min(float64(rl.limit.Burst), b.tokens+now.Sub(b.at).Seconds()*rl.limit.Rate)
+}
+
+// sweep forgets clients whose buckets have refilled, which is the same as
+// never having seen them. It must be called with rl.mx held.
+func (rl *rateLimiter) sweep(now time.Time) {
+	for client, b := range rl.buckets {
+		if rl.refilled(b, now) >= float64(rl.limit.Burst) {
+			delete(rl.buckets, client)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// clientKey is who made r: the client it was authenticated as, or its IP
+// address if it wasn't. Unverified tokens are ignored, so that clients can't
+// dodge their limit by sending a new one with each request.
+func clientKey(r *http.Request) string {
+	if subject, ok := r.Context().Value(subjectKey{}).(string); ok {
+		return subject
+	}
+	return "ip:" + remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// setRetryAfter tells the client to wait at least d, in whole seconds.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := max(1, int(math.Ceil(d.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
@@ -0,0 +1,123 @@
+package httpadapter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_RateLimit(t *testing.T) {
+	setup := func(t *testing.T, opts ...lift.Option) (*http.ServeMux, *lift.LiftService) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub(), opts...)
+		return NewController(http.NewServeMux(), svc), svc
+	}
+	get := func(server http.Handler, remoteAddr, token string) *http.Response {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/lift", nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		server.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	t.Run("clients are limited to their burst", func(t *testing.T) {
+		mux, _ := setup(t)
+		server := NewRateLimit(mux, RateLimit{Rate: 0.5, Burst: 2})
+		for i := 0; i < 2; i++ {
+			if res := get(server, "10.0.0.1:1234", ""); res.StatusCode != 200 {
+				t.Fatalf("expected 200, got %d", res.StatusCode)
+			}
+		}
+		res := get(server, "10.0.0.1:5678", "")
+		if res.StatusCode != 429 {
+			t.Fatalf("expected 429, got %d", res.StatusCode)
+		}
+		if got := res.Header.Get("Retry-After"); got != "2" {
+			t.Errorf("expected Retry-After 2, got %q", got)
+		}
+		if res := get(server, "10.0.0.2:1234", ""); res.StatusCode != 200 {
+			t.Errorf("expected another client to have its own bucket, got %d", res.StatusCode)
+		}
+		if res := get(server, "10.0.0.1:1234", "made-up"); res.StatusCode != 429 {
+			t.Errorf("expected an unverified token not to get its own bucket, got %d", res.StatusCode)
+		}
+	})
+
+	t.Run("authenticated clients are limited by who they are", func(t *testing.T) {
+		mux, _ := setup(t)
+		secret := []byte("s3cret")
+		server := NewAuth(mux,
+			WithAPIKey("viewer-key", RoleViewer),
+			WithJWTSecret(secret),
+			WithRateLimit(RateLimit{Rate: 0.5, Burst: 1}),
+		)
+		alice, _ := NewToken(secret, Claims{Subject: "alice", Role: RoleViewer})
+		bob, _ := NewToken(secret, Claims{Subject: "bob", Role: RoleViewer})
+
+		if res := get(server, "10.0.0.1:1234", alice); res.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d", res.StatusCode)
+		}
+		if res := get(server, "10.0.0.2:1234", alice); res.StatusCode != 429 {
+			t.Errorf("expected alice to be limited from any address, got %d", res.StatusCode)
+		}
+		if res := get(server, "10.0.0.1:1234", bob); res.StatusCode != 200 {
+			t.Errorf("expected bob to have a bucket of their own, got %d", res.StatusCode)
+		}
+		if res := get(server, "10.0.0.1:1234", "viewer-key"); res.StatusCode != 200 {
+			t.Errorf("expected the API key to have its own bucket, got %d", res.StatusCode)
+		}
+
+		// every made up token is limited by the address it comes from
+		if res := get(server, "10.0.0.3:1234", "made-up-1"); res.StatusCode != 401 {
+			t.Errorf("expected 401, got %d", res.StatusCode)
+		}
+		if res := get(server, "10.0.0.3:1234", "made-up-2"); res.StatusCode != 429 {
+			t.Errorf("expected 429, got %d", res.StatusCode)
+		}
+	})
+
+	t.Run("buckets refill over time", func(t *testing.T) {
+		rl := &rateLimiter{limit: RateLimit{Rate: 1, Burst: 1}, buckets: make(map[string]*bucket)}
+		now := time.Now()
+		if _, ok := rl.take("a", now); !ok {
+			t.Fatal("expected the first request to be allowed")
+		}
+		if wait, ok := rl.take("a", now.Add(200*time.Millisecond)); ok || wait != 800*time.Millisecond {
+			t.Errorf("expected to wait 800ms, got %s", wait)
+		}
+		if _, ok := rl.take("a", now.Add(time.Second)); !ok {
+			t.Error("expected the bucket to have refilled")
+		}
+	})
+
+	t.Run("lifts with too many stops respond 429", func(t *testing.T) {
+		server, svc := setup(t, lift.WithMaxPendingStops(1))
+		l, _ := svc.AddLift(context.Background(), lift.LiftConfig{Floor: 0, FloorDelayMs: 60_000, DoorDwellMs: 1000})
+		var res *http.Response
+		for floor := 1; floor <= 10; floor++ {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/lift/"+l.Id.String()+"/call", strings.NewReader(fmt.Sprintf(`{"floor":%d}`, floor)))
+			server.ServeHTTP(rec, req)
+			if res = rec.Result(); res.StatusCode != 201 {
+				break
+			}
+		}
+		if res.StatusCode != 429 {
+			t.Fatalf("expected 429, got %d", res.StatusCode)
+		}
+		if got := res.Header.Get("Retry-After"); got != "61" {
+			t.Errorf("expected Retry-After 61, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package httpadapter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/leow93/miffed-api/internal/config"
+)
+
+// ReloadFunc reloads the building's config, returning how its lifts changed.
+type ReloadFunc func(ctx context.Context) ([]config.Change, error)
+
+type reloadRes struct {
+	Changes []config.Change `json:"changes"`
+	// Errors lists the lifts that couldn't be changed. They are tried again on
+	// the next reload.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func reloadHandler(reload ReloadFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		changes, err := reload(r.Context())
+		var notApplied *config.NotAppliedError
+		if err != nil && !errors.As(err, &notApplied) {
+			errResponse(w, liftErrStatus(err), err)
+			return
+		}
+
+		res := reloadRes{Changes: changes}
+		if res.Changes == nil {
+			res.Changes = []config.Change{}
+		}
+		if notApplied != nil {
+			for _, err := range notApplied.Errs {
+				res.Errors = append(res.Errors, err.Error())
+			}
+		}
+		okResponse(w, 200, res)
+	})
+}
+
+// WithReload serves POST /admin/reload, which calls reload.
+func WithReload(reload ReloadFunc) Option {
+	return func(opts *controllerOptions) {
+		opts.reload = reload
+	}
+}
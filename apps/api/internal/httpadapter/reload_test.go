@@ -0,0 +1,86 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/config"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Reload(t *testing.T) {
+	setup := func(t *testing.T, opts ...Option) (*http.ServeMux, *lift.LiftService) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		return NewController(http.NewServeMux(), svc, opts...), svc
+	}
+	post := func(server http.Handler, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rec
+	}
+
+	t.Run("reloading responds with what changed", func(t *testing.T) {
+		id := lift.NewLiftId()
+		changes := []config.Change{{Lift: "A", LiftId: id, Type: config.ChangeAdded}}
+		server, _ := setup(t, WithReload(func(context.Context) ([]config.Change, error) {
+			return changes, &config.NotAppliedError{Errs: []error{errors.New("adding lift B: shaft full")}}
+		}))
+		rec := post(server, "/admin/reload", "")
+		var res reloadRes
+		json.NewDecoder(rec.Body).Decode(&res)
+		want := reloadRes{Changes: changes, Errors: []string{"adding lift B: shaft full"}}
+		if rec.Code != 200 || !reflect.DeepEqual(res, want) {
+			t.Errorf("expected 200 %+v, got %d %+v", want, rec.Code, res)
+		}
+	})
+
+	t.Run("invalid configs are refused", func(t *testing.T) {
+		server, _ := setup(t, WithReload(func(context.Context) ([]config.Change, error) {
+			return nil, &config.InvalidError{Problems: []string{"listen: must be an address"}}
+		}))
+		rec := post(server, "/admin/reload", "")
+		var res errorResponse
+		json.NewDecoder(rec.Body).Decode(&res)
+		if rec.Code != 400 || res.ErrorCode != CodeInvalidConfig {
+			t.Errorf("expected 400 %s, got %d %+v", CodeInvalidConfig, rec.Code, res)
+		}
+	})
+
+	t.Run("reloading is only served when configured", func(t *testing.T) {
+		server, _ := setup(t)
+		if rec := post(server, "/admin/reload", ""); rec.Code != 404 {
+			t.Errorf("expected 404, got %d", rec.Code)
+		}
+	})
+
+	t.Run("retiring lifts refuse calls", func(t *testing.T) {
+		server, svc := setup(t)
+		l, _ := svc.AddLift(context.Background(), lift.LiftConfig{Floor: 0, FloorDelayMs: 1000})
+		svc.CallLift(context.Background(), l.Id, 5)
+		svc.RetireLift(context.Background(), l.Id)
+
+		rec := post(server, "/lift/"+l.Id.String()+"/call", `{"floor": 3}`)
+		var res errorResponse
+		json.NewDecoder(rec.Body).Decode(&res)
+		if rec.Code != 409 || res.ErrorCode != CodeLiftRetiring {
+			t.Errorf("expected 409 %s, got %d %+v", CodeLiftRetiring, rec.Code, res)
+		}
+
+		rec = httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest("GET", "/lift/"+l.Id.String(), nil))
+		var got getLiftRes
+		json.NewDecoder(rec.Body).Decode(&got)
+		if !got.Retiring {
+			t.Errorf("expected the lift to be shown as retiring, got %+v", got)
+		}
+	})
+}
@@ -2,26 +2,75 @@ package httpadapter
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/leow93/miffed-api/internal/lift"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		if r.Host == "localhost:8080" {
-			return true
-		}
+func newUpgrader(origins []string) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			if origins == nil {
+				return r.Host == "localhost:8080" || r.Header.Get("Origin") == r.Host
+			}
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				// not a browser, so not a cross-origin request
+				return true
+			}
+			if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+				return true
+			}
+			return slices.ContainsFunc(origins, func(allowed string) bool {
+				return originMatches(allowed, origin)
+			})
+		},
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			errResponse(w, status, reason)
+		},
+	}
+}
+
+// originMatches reports whether origin is allowed by pattern, which is "*"
+// for any origin or an origin that may contain one wildcard, such as
+// "https://*.example.com", as CORS origins are.
+func originMatches(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	prefix, suffix, wildcard := strings.Cut(pattern, "*")
+	if !wildcard {
+		return pattern == origin
+	}
+	return len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
 
-		originHeader := r.Header.Get("Origin")
-		return originHeader == r.Host
-	},
+var errUnsupportedMessage = errors.New("the socket only sends events")
+
+// socketConn serialises writes to a connection, which is written to by both
+// the event writer and the reader when it rejects a message.
+type socketConn struct {
+	*websocket.Conn
+	mx sync.Mutex
+}
+
+func (c *socketConn) writeJSON(v any) error {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.WriteMessage(websocket.TextMessage, bytes)
 }
 
-func writer(c *websocket.Conn, manager *lift.SubscriptionManager, id uuid.UUID, ch <-chan lift.LiftEvent) {
+func writer(c *socketConn, manager *lift.SubscriptionManager, id uuid.UUID, ch <-chan lift.LiftEvent) {
 	defer func() {
 		manager.Unsubscribe(id)
 		c.Close()
@@ -29,43 +78,68 @@ func writer(c *websocket.Conn, manager *lift.SubscriptionManager, id uuid.UUID,
 
 	for {
 		msg := <-ch
-		w, err := c.NextWriter(websocket.TextMessage)
-		if err != nil {
+		if err := c.writeJSON(&msg); err != nil {
 			return
 		}
+	}
+}
 
-		bytes, err := json.Marshal(&msg)
-		if err != nil {
+// reader answers anything a client sends with an error, in the same form as
+// REST error responses, and closes the connection once the client goes.
+func reader(c *socketConn) {
+	defer c.Close()
+	for {
+		if _, _, err := c.ReadMessage(); err != nil {
 			return
 		}
-		w.Write(bytes)
-
-		if err := w.Close(); err != nil {
+		if err := c.writeJSON(newErrorResponse(400, errUnsupportedMessage)); err != nil {
 			return
 		}
-
 	}
 }
 
-func socketHandler(subscriptionMgr *lift.SubscriptionManager) http.Handler {
+func socketHandler(subscriptionMgr *lift.SubscriptionManager, upgrader *websocket.Upgrader) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c, err := upgrader.Upgrade(w, r, nil)
+		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println("error upgrading connection", err)
 			return
 		}
+		c := &socketConn{Conn: conn}
 
 		id, ch, err := subscriptionMgr.Subscribe()
 		if err != nil {
 			log.Println("error subscribing", err)
+			c.writeJSON(newErrorResponse(500, err))
+			c.Close()
 			return
 		}
 
 		go writer(c, subscriptionMgr, id, ch)
+		go reader(c)
 	})
 }
 
-func NewSocket(mux *http.ServeMux, subs *lift.SubscriptionManager) *http.ServeMux {
-	mux.Handle("/socket", socketHandler(subs))
+type socketOptions struct {
+	origins []string
+}
+
+type SocketOption func(opts *socketOptions)
+
+// WithOrigins lets browsers on origins open the socket, in the same form as
+// the origins given to the CORS middleware. The socket is always open to its
+// own origin.
+func WithOrigins(origins ...string) SocketOption {
+	return func(opts *socketOptions) {
+		opts.origins = append(opts.origins, origins...)
+	}
+}
+
+func NewSocket(mux *http.ServeMux, subs *lift.SubscriptionManager, opts ...SocketOption) *http.ServeMux {
+	var o socketOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	mux.Handle("/socket", socketHandler(subs, newUpgrader(o.origins)))
 	return mux
 }
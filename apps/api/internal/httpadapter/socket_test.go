@@ -59,4 +59,37 @@ func Test_Socket(t *testing.T) {
 			t.Errorf("expected lift_added, got %s", event.EventType)
 		}
 	})
+	t.Run("browsers on the configured origins can connect", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		subs := lift.NewSubscriptionManager(ctx, pubsub.NewMemoryPubSub())
+		server := httptest.NewServer(NewSocket(http.NewServeMux(), subs, WithOrigins("https://miffed.example", "https://*.lifts.example")))
+		defer server.Close()
+		wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/socket"
+
+		tests := []struct {
+			origin string
+			ok     bool
+		}{
+			{"https://miffed.example", true},
+			{"https://east.lifts.example", true},
+			{server.URL, true},
+			{"", true},
+			{"https://evil.example", false},
+			{"http://miffed.example", false},
+		}
+		for _, test := range tests {
+			header := http.Header{}
+			if test.origin != "" {
+				header.Set("Origin", test.origin)
+			}
+			ws, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+			if (err == nil) != test.ok {
+				t.Errorf("origin %q: expected connecting to succeed %v, got %v", test.origin, test.ok, err)
+			}
+			if ws != nil {
+				ws.Close()
+			}
+		}
+	})
 }
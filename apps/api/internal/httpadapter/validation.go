@@ -0,0 +1,172 @@
+package httpadapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// maxBodyBytes is the largest request body accepted.
+const maxBodyBytes = 1 << 20
+
+// fieldError is a problem with one field of a request's body, path or query.
+type fieldError struct {
+	Field   string    `json:"field,omitempty"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+}
+
+// validationError lists every problem found with a request.
+type validationError []fieldError
+
+func (e validationError) Error() string {
+	messages := make([]string, len(e))
+	for i, f := range e {
+		messages[i] = f.Message
+		if f.Field != "" {
+			messages[i] = f.Field + ": " + f.Message
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e validationError) status() int {
+	if len(e) > 0 && e[0].Code == CodeRequestTooLarge {
+		return 413
+	}
+	return 400
+}
+
+// floorLimits are the floors requests may refer to.
+type floorLimits struct {
+	lowest, highest int
+}
+
+// defaultFloorLimits allow for any building yet built, and then some.
+var defaultFloorLimits = floorLimits{lowest: -100, highest: 1000}
+
+type validator struct {
+	floors floorLimits
+	errs   validationError
+}
+
+func (v *validator) add(field string, code ErrorCode, format string, args ...any) {
+	v.errs = append(v.errs, fieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// required checks a field was given, reporting whether it was.
+func (v *validator) required(field string, given bool) bool {
+	if !given {
+		v.add(field, CodeMissingField, "is required")
+	}
+	return given
+}
+
+func (v *validator) floor(field string, floor int) {
+	if floor < v.floors.lowest || floor > v.floors.highest {
+		v.add(field, CodeFloorOutOfRange, "must be between %d and %d", v.floors.lowest, v.floors.highest)
+	}
+}
+
+func (v *validator) nonNegative(field string, n int) {
+	if n < 0 {
+		v.add(field, CodeInvalidValue, "must not be negative")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// request is a request body that can check its own fields.
+type request interface {
+	validate(v *validator)
+}
+
+// decodeBody decodes and validates a JSON request body. An empty body is
+// treated as an empty object.
+func decodeBody[T request](w http.ResponseWriter, r *http.Request, floors floorLimits) (T, error) {
+	return decode[T](http.MaxBytesReader(w, r.Body, maxBodyBytes), floors)
+}
+
+func decode[T request](reader io.Reader, floors floorLimits) (T, error) {
+	var body T
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&body)
+	if err == nil && decoder.More() {
+		err = errors.New("unexpected data after the request body")
+	}
+	if err != nil && err != io.EOF {
+		return body, decodeError(err)
+	}
+
+	v := validator{floors: floors}
+	body.validate(&v)
+	return body, v.err()
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &typeErr):
+		return validationError{{Field: typeErr.Field, Code: CodeInvalidType, Message: "must be " + jsonType(typeErr.Type.String())}}
+	case errors.As(err, &tooLarge):
+		return validationError{{Code: CodeRequestTooLarge, Message: fmt.Sprintf("body must be at most %d bytes", tooLarge.Limit)}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return validationError{{Field: field, Code: CodeUnknownField, Message: "is not a known field"}}
+	}
+	return validationError{{Code: CodeInvalidJSON, Message: "body is not valid JSON: " + err.Error()}}
+}
+
+func jsonType(goType string) string {
+	switch {
+	case strings.HasPrefix(goType, "int"), strings.HasPrefix(goType, "*int"):
+		return "an integer"
+	case strings.HasPrefix(goType, "float"):
+		return "a number"
+	case goType == "bool":
+		return "a boolean"
+	case strings.HasPrefix(goType, "[]"):
+		return "an array"
+	case strings.HasSuffix(goType, "string") || goType == "lift.FaultType":
+		return "a string"
+	}
+	return "an object"
+}
+
+// liftIdParam is the lift id in a request's path.
+func liftIdParam(r *http.Request) (lift.LiftId, error) {
+	id, err := lift.ParseLiftId(r.PathValue("id"))
+	if err != nil {
+		return id, validationError{{Field: "id", Code: CodeInvalidLiftId, Message: "must be a UUID"}}
+	}
+	return id, nil
+}
+
+// floorQuery is the floor given in a request's query.
+func floorQuery(r *http.Request, floors floorLimits) (int, error) {
+	v := validator{floors: floors}
+	raw := r.URL.Query().Get("floor")
+	if !v.required("floor", raw != "") {
+		return 0, v.err()
+	}
+	floor, err := strconv.Atoi(raw)
+	if err != nil {
+		v.add("floor", CodeInvalidType, "must be an integer")
+		return 0, v.err()
+	}
+	v.floor("floor", floor)
+	return floor, v.err()
+}
@@ -0,0 +1,119 @@
+package httpadapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Validation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	server := NewController(http.NewServeMux(), svc, WithFloors(0, 20))
+	l, _ := svc.AddLift(ctx, lift.LiftConfig{Floor: 0})
+	liftPath := "/lift/" + l.Id.String()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   ErrorCode
+		field  string
+	}{
+		{"malformed JSON", "POST", "/lift", `{"floor":`, 400, CodeInvalidJSON, ""},
+		{"unknown fields", "POST", "/lift", `{"flor": 1}`, 400, CodeUnknownField, "flor"},
+		{"wrong types", "POST", "/lift", `{"floor": "10"}`, 400, CodeInvalidType, "floor"},
+		{"negative floor delays", "POST", "/lift", `{"floor_delay_ms": -1}`, 400, CodeInvalidValue, "floor_delay_ms"},
+		{"floors out of range", "POST", "/lift", `{"floor": 21}`, 400, CodeFloorOutOfRange, "floor"},
+		{"backwards served floors", "POST", "/lift", `{"served_floors": [{"from": 5, "to": 1}]}`, 400, CodeInvalidFloorRange, "served_floors[0]"},
+		{"too many decks", "POST", "/lift", `{"decks": 3}`, 400, CodeInvalidDecks, "decks"},
+		{"calls without a floor", "POST", liftPath + "/call", `{}`, 400, CodeMissingField, "floor"},
+		{"calls to floors out of range", "POST", liftPath + "/call", `{"floor": -1}`, 400, CodeFloorOutOfRange, "floor"},
+		{"lift create bodies on calls", "POST", liftPath + "/call", `{"floor": 1, "decks": 2}`, 400, CodeUnknownField, "decks"},
+		{"car calls without a floor", "POST", liftPath + "/car-call", `{"credential": "badge"}`, 400, CodeMissingField, "floor"},
+		{"dispatch to the same floor", "POST", "/call", `{"floor": 2, "destination": 2}`, 400, CodeInvalidValue, "destination"},
+		{"recalls without a floor", "POST", "/admin/emergency-recall", `{}`, 400, CodeMissingField, "floor"},
+		{"faults without a type", "POST", "/admin/lift/" + l.Id.String() + "/faults", `{"drop_rate": 2}`, 400, CodeMissingField, "type"},
+		{"invalid lift ids", "GET", "/lift/123", "", 400, CodeInvalidLiftId, "id"},
+		{"unknown lifts", "GET", "/lift/" + lift.NewLiftId().String(), "", 404, CodeLiftNotFound, ""},
+		{"invalid wait timeouts", "POST", liftPath + "/call?wait=true&timeout=soon", `{"floor": 1}`, 400, CodeInvalidValue, "timeout"},
+		{"eta without a floor", "GET", "/eta", "", 400, CodeMissingField, "floor"},
+		{"eta for a floor out of range", "GET", liftPath + "/eta?floor=99", "", 400, CodeFloorOutOfRange, "floor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("expected %d, got %d", tt.status, rec.Code)
+			}
+			var res errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Code != tt.status || res.ErrorCode != tt.code || res.Error == nil {
+				t.Errorf("expected %d %s, got %+v", tt.status, tt.code, res)
+			}
+			if tt.field != "" && (len(res.Fields) == 0 || res.Fields[0].Field != tt.field) {
+				t.Errorf("expected field %s to be at fault, got %+v", tt.field, res.Fields)
+			}
+		})
+	}
+
+	t.Run("every problem is reported", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"floor": 99, "floor_delay_ms": -1, "door_dwell_ms": -1}`)
+		server.ServeHTTP(rec, httptest.NewRequest("POST", "/lift", body))
+		var res errorResponse
+		json.NewDecoder(rec.Body).Decode(&res)
+		if len(res.Fields) != 3 {
+			t.Errorf("expected 3 fields at fault, got %+v", res.Fields)
+		}
+	})
+}
+
+func Test_SocketErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subs := lift.NewSubscriptionManager(ctx, pubsub.NewMemoryPubSub())
+	server := httptest.NewServer(NewSocket(http.NewServeMux(), subs))
+	defer server.Close()
+
+	t.Run("messages from clients are rejected", func(t *testing.T) {
+		ws := ensureWsConnection(t, server)
+		defer ws.Close()
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"floor": 1}`)); err != nil {
+			t.Fatal(err)
+		}
+		var res errorResponse
+		if err := json.Unmarshal(readTextMessage(t, ws), &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.ErrorCode != CodeUnsupportedMessage {
+			t.Errorf("expected %s, got %+v", CodeUnsupportedMessage, res)
+		}
+	})
+
+	t.Run("failed upgrades use the error schema", func(t *testing.T) {
+		res, err := http.Get(server.URL + "/socket")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var body errorResponse
+		json.NewDecoder(res.Body).Decode(&body)
+		if res.StatusCode != 400 || body.ErrorCode != CodeBadRequest {
+			t.Errorf("expected 400 %s, got %d %+v", CodeBadRequest, res.StatusCode, body)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package lift
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"time"
+)
+
+var ErrAccessDenied = errors.New("access to floor denied")
+
+// AccessRule restricts a floor to passengers holding one of Credentials,
+// except while one of its Public schedules is in effect. A floor with several
+// rules is open to anyone any of them lets in.
+type AccessRule struct {
+	Floor       int
+	Credentials []string
+	Public      []Schedule
+}
+
+// Schedule is a daily window of time, measured from midnight on the service's
+// clock. A window whose To is before its From runs overnight, and belongs to
+// the day it starts on.
+type Schedule struct {
+	Days []time.Weekday // every day if empty
+	From time.Duration
+	To   time.Duration
+}
+
+func (s Schedule) covers(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	day := t.Weekday()
+	switch {
+	case s.From <= s.To:
+		return s.on(day) && offset >= s.From && offset < s.To
+	case offset >= s.From:
+		return s.on(day)
+	case offset < s.To:
+		return s.on((day + 6) % 7)
+	}
+	return false
+}
+
+func (s Schedule) on(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (r AccessRule) allows(credential string, now time.Time) bool {
+	for _, schedule := range r.Public {
+		if schedule.covers(now) {
+			return true
+		}
+	}
+	if credential == "" {
+		return false
+	}
+	for _, c := range r.Credentials {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(credential)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// WithAccessRules restricts which passengers can travel to which floors.
+func WithAccessRules(rules ...AccessRule) Option {
+	return func(svc *LiftService) {
+		svc.access = accessByFloor(rules)
+	}
+}
+
+// SetAccessRules replaces the service's access rules, taking effect from the
+// next call.
+func (svc *LiftService) SetAccessRules(rules ...AccessRule) {
+	access := accessByFloor(rules)
+	svc.mx.Lock()
+	svc.access = access
+	svc.mx.Unlock()
+}
+
+func accessByFloor(rules []AccessRule) map[int][]AccessRule {
+	access := make(map[int][]AccessRule)
+	for _, rule := range rules {
+		access[rule.Floor] = append(access[rule.Floor], rule)
+	}
+	return access
+}
+
+type credentialKey struct{}
+
+// WithCredential attaches a passenger's badge or token to ctx, for car calls
+// to restricted floors.
+func WithCredential(ctx context.Context, credential string) context.Context {
+	return context.WithValue(ctx, credentialKey{}, credential)
+}
+
+func credentialFrom(ctx context.Context) string {
+	credential, _ := ctx.Value(credentialKey{}).(string)
+	return credential
+}
+
+// authorise checks the credential in ctx lets a passenger travel to floor,
+// publishing an access_denied event if not.
+func (svc *LiftService) authorise(ctx context.Context, id LiftId, floor int) error {
+	svc.mx.Lock()
+	rules, restricted := svc.access[floor]
+	svc.mx.Unlock()
+	if !restricted {
+		return nil
+	}
+	credential, now := credentialFrom(ctx), svc.clock.Now()
+	for _, rule := range rules {
+		if rule.allows(credential, now) {
+			return nil
+		}
+	}
+	svc.publishEvent(createLiftEvent(id, "access_denied", AccessDenied{Floor: floor}))
+	return ErrAccessDenied
+}
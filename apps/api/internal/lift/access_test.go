@@ -0,0 +1,103 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Schedule(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	officeHours := Schedule{Days: []time.Weekday{time.Monday}, From: 8 * time.Hour, To: 18 * time.Hour}
+	overnight := Schedule{Days: []time.Weekday{time.Monday}, From: 22 * time.Hour, To: 6 * time.Hour}
+
+	tests := []struct {
+		schedule Schedule
+		at       time.Time
+		want     bool
+	}{
+		{officeHours, monday.Add(9 * time.Hour), true},
+		{officeHours, monday.Add(18 * time.Hour), false},
+		{officeHours, monday.Add(24*time.Hour + 9*time.Hour), false},
+		{overnight, monday.Add(23 * time.Hour), true},
+		{overnight, monday.Add(24*time.Hour + 5*time.Hour), true},
+		{overnight, monday.Add(5 * time.Hour), false},
+		{Schedule{From: 0, To: 24 * time.Hour}, monday.Add(3 * 24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		if got := tt.schedule.covers(tt.at); got != tt.want {
+			t.Errorf("%+v at %s: expected %v, got %v", tt.schedule, tt.at.Format(time.RFC1123), tt.want, got)
+		}
+	}
+}
+
+func Test_AccessControl(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) (context.Context, *LiftService, *clock.Virtual, Lift, <-chan LiftEvent) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		v := clock.NewVirtual(monday)
+		svc := NewLiftService(ctx, ps, WithClock(v), WithAccessRules(
+			AccessRule{Floor: 9, Credentials: []string{"badge-1"}},
+			AccessRule{Floor: 5, Credentials: []string{"badge-2"}, Public: []Schedule{{From: 8 * time.Hour, To: 18 * time.Hour}}},
+		))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		t.Cleanup(func() { subs.Unsubscribe(id) })
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		return ctx, svc, v, l, ch
+	}
+
+	t.Run("restricted floors need a credential", func(t *testing.T) {
+		ctx, svc, _, l, ch := setup(t)
+		if _, err := svc.CarCall(ctx, l.Id, 9); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("expected ErrAccessDenied, got %v", err)
+		}
+		ev := nextEvent(t, ch, "access_denied")
+		if ev.LiftId != l.Id || ev.Data != (AccessDenied{Floor: 9}) {
+			t.Errorf("expected access to floor 9 denied, got %+v", ev)
+		}
+		if _, err := svc.CarCall(WithCredential(ctx, "badge-2"), l.Id, 9); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("expected the wrong badge to be denied, got %v", err)
+		}
+		if _, err := svc.CarCall(WithCredential(ctx, "badge-1"), l.Id, 9); err != nil {
+			t.Errorf("expected the right badge to be let in, got %v", err)
+		}
+		if _, err := svc.CarCall(ctx, l.Id, 3); err != nil {
+			t.Errorf("expected unrestricted floors to be open, got %v", err)
+		}
+	})
+
+	t.Run("floors are open to anyone while public", func(t *testing.T) {
+		ctx, svc, v, l, _ := setup(t)
+		if _, err := svc.CarCall(ctx, l.Id, 5); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("expected floor 5 to be restricted before 8am, got %v", err)
+		}
+		v.Advance(2 * time.Hour)
+		if _, err := svc.CarCall(ctx, l.Id, 5); err != nil {
+			t.Errorf("expected floor 5 to be public at 9am, got %v", err)
+		}
+	})
+
+	t.Run("destination dispatch checks the destination", func(t *testing.T) {
+		ctx, svc, _, _, _ := setup(t)
+		if _, _, err := svc.DispatchTo(ctx, 0, 9); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("expected ErrAccessDenied, got %v", err)
+		}
+	})
+	t.Run("access rules can be replaced", func(t *testing.T) {
+		ctx, svc, _, l, _ := setup(t)
+		svc.SetAccessRules(AccessRule{Floor: 3, Credentials: []string{"badge-3"}})
+		if _, err := svc.CarCall(ctx, l.Id, 9); err != nil {
+			t.Errorf("expected floor 9 to be open, got %v", err)
+		}
+		if _, err := svc.CarCall(ctx, l.Id, 3); !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("expected floor 3 to be restricted, got %v", err)
+		}
+	})
+}
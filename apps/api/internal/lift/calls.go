@@ -0,0 +1,100 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCallCancelled = errors.New("call cancelled")
+
+// Call records a single request for a lift and when it was dealt with.
+type Call struct {
+	Id          CallId
+	LiftId      LiftId
+	Floor       int
+	CarCall     bool
+	Deck        Deck // which deck served the call, for a double-deck lift
+	RequestedAt time.Time
+	AssignedAt  time.Time
+	ArrivedAt   time.Time
+}
+
+// WaitTime is how long the caller waited for the lift, or zero if the lift has
+// not yet arrived.
+func (c Call) WaitTime() time.Duration {
+	if c.ArrivedAt.IsZero() {
+		return 0
+	}
+	return c.ArrivedAt.Sub(c.RequestedAt)
+}
+
+// CallHandle lets a caller wait for the lift it called to arrive.
+type CallHandle struct {
+	call      Call
+	deck      Deck // set by the lift before it arrives
+	done      chan struct{}
+	once      sync.Once
+	arrivedAt time.Time
+	err       error
+}
+
+func newCallHandle(floor int, requestedAt time.Time) *CallHandle {
+	return &CallHandle{
+		call: Call{Id: NewCallId(), Floor: floor, RequestedAt: requestedAt},
+		done: make(chan struct{}),
+	}
+}
+
+// Done is closed once the lift has arrived or the call has been cancelled.
+func (h *CallHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Call returns the call, including its arrival time once Done is closed.
+func (h *CallHandle) Call() Call {
+	call := h.call
+	select {
+	case <-h.done:
+		call.ArrivedAt = h.arrivedAt
+		call.Deck = h.deck
+	default:
+	}
+	return call
+}
+
+// Err returns ErrCallCancelled if the call was dropped before the lift arrived.
+func (h *CallHandle) Err() error {
+	select {
+	case <-h.done:
+		return h.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the lift arrives, the call is cancelled or ctx is done.
+func (h *CallHandle) Wait(ctx context.Context) (Call, error) {
+	select {
+	case <-ctx.Done():
+		return h.Call(), ctx.Err()
+	case <-h.done:
+		return h.Call(), h.err
+	}
+}
+
+func (h *CallHandle) arrive(at time.Time) Call {
+	h.once.Do(func() {
+		h.arrivedAt = at
+		close(h.done)
+	})
+	return h.Call()
+}
+
+func (h *CallHandle) cancel() {
+	h.once.Do(func() {
+		h.err = ErrCallCancelled
+		close(h.done)
+	})
+}
@@ -0,0 +1,86 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_CallHandle(t *testing.T) {
+	t.Run("done is closed when the lift arrives", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 5})
+
+		handle, err := svc.CallLift(ctx, lift.Id, 3)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		select {
+		case <-handle.Done():
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the lift")
+		}
+
+		call := handle.Call()
+		if call.ArrivedAt.IsZero() || call.ArrivedAt.Before(call.RequestedAt) {
+			t.Errorf("expected an arrival time after the request, got %+v", call)
+		}
+		if err := handle.Err(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("wait returns the call once the lift arrives", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 5})
+
+		handle, _ := svc.CallLift(ctx, lift.Id, 2)
+		waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+		defer waitCancel()
+		call, err := handle.Wait(waitCtx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if call.Floor != 2 || call.ArrivedAt.IsZero() {
+			t.Errorf("expected arrival at floor 2, got %+v", call)
+		}
+	})
+
+	t.Run("wait gives up when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 100})
+
+		handle, _ := svc.CallLift(ctx, lift.Id, 10)
+		waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer waitCancel()
+		if _, err := handle.Wait(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("calls dropped by maintenance are cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 20})
+
+		handle, _ := svc.CallLift(ctx, lift.Id, 10)
+		time.Sleep(10 * time.Millisecond)
+		svc.TakeOutOfService(ctx, lift.Id, AbandonTrip)
+
+		waitCtx, waitCancel := context.WithTimeout(ctx, time.Second)
+		defer waitCancel()
+		if _, err := handle.Wait(waitCtx); !errors.Is(err, ErrCallCancelled) {
+			t.Errorf("expected call cancelled error, got %v", err)
+		}
+	})
+}
@@ -1,6 +1,10 @@
 package lift
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -10,19 +14,28 @@ func NewLiftId() LiftId {
 	return LiftId{uuid.New()}
 }
 
+var ErrInvalidLiftId = errors.New("invalid lift id")
+
 func ParseLiftId(id string) (LiftId, error) {
 	ID, err := uuid.Parse(id)
 	if err != nil {
-		return LiftId{}, nil
+		return LiftId{}, fmt.Errorf("%w %q: %w", ErrInvalidLiftId, id, err)
 	}
 
 	return LiftId{ID}, nil
 }
 
+type CallId struct{ uuid.UUID }
+
+func NewCallId() CallId {
+	return CallId{uuid.New()}
+}
+
 type LiftEvent struct {
-	Data      any    `json:"data"`
-	EventType string `json:"event_type"`
-	LiftId    LiftId `json:"lift_id"`
+	Data       any       `json:"data"`
+	EventType  string    `json:"event_type"`
+	LiftId     LiftId    `json:"lift_id"`
+	OccurredAt time.Time `json:"occurred_at"`
 }
 
 func createLiftEvent(liftId LiftId, eventType string, data any) LiftEvent {
@@ -45,3 +58,59 @@ type LiftTransited struct {
 type LiftArrived struct {
 	Floor int `json:"floor"`
 }
+
+type LiftStatusChanged struct {
+	Status LiftStatus `json:"status"`
+}
+
+type LiftDoorsOpened struct {
+	Floor int `json:"floor"`
+}
+
+type LiftDoorsClosed struct {
+	Floor int `json:"floor"`
+}
+
+type EmergencyRecallStarted struct {
+	Floor int `json:"floor"`
+}
+
+type EmergencyRecallCleared struct{}
+
+type LiftFaultRaised struct {
+	Fault FaultType `json:"fault"`
+	Floor int       `json:"floor"`
+}
+
+type LiftFaultCleared struct {
+	Fault FaultType `json:"fault"`
+	Floor int       `json:"floor"`
+}
+
+type LiftCallServed struct {
+	CallId CallId `json:"call_id"`
+	Floor  int    `json:"floor"`
+	WaitMs int64  `json:"wait_ms"`
+	Deck   Deck   `json:"deck,omitempty"`
+	Car    Car    `json:"car,omitempty"`
+}
+
+type LiftEnergy struct {
+	Floor   int     `json:"floor"`
+	TripWh  float64 `json:"trip_wh"`
+	TotalWh float64 `json:"total_wh"`
+}
+
+type LiftParking struct {
+	From   int           `json:"from"`
+	Floor  int           `json:"floor"`
+	Policy ParkingPolicy `json:"policy"`
+}
+
+type LiftParkingCancelled struct {
+	Floor int `json:"floor"`
+}
+
+type AccessDenied struct {
+	Floor int `json:"floor"`
+}
@@ -0,0 +1,87 @@
+package lift
+
+import (
+	"errors"
+	"slices"
+)
+
+// Deck is which deck of a double-deck lift served a call.
+type Deck string
+
+const (
+	DeckLower Deck = "lower"
+	DeckUpper Deck = "upper"
+)
+
+var ErrInvalidDecks = errors.New("a lift has one or two decks")
+
+// WithLowestFloor sets the building's lowest floor, below which a lift that
+// serves every floor can't go. It is negative for a building with basements,
+// and defaults to 0.
+func WithLowestFloor(floor int) Option {
+	return func(svc *LiftService) {
+		svc.lowestFloor = floor
+	}
+}
+
+// lowestFloor is the lowest floor the lift serves, which is the building's
+// lowest if it serves every floor.
+func (lift *liftModel) lowestFloor() int {
+	if len(lift.ServedFloors) == 0 {
+		return lift.lowest
+	}
+	lowest := lift.ServedFloors[0].From
+	for _, r := range lift.ServedFloors[1:] {
+		lowest = min(lowest, r.From)
+	}
+	return lowest
+}
+
+// deckServes reports whether a deck of the lift can stop at floor.
+func (lift *liftModel) deckServes(floor int) bool {
+	return floor >= lift.lowestFloor() && serves(lift.ServedFloors, floor)
+}
+
+// stopFor is where the lift stops to serve floor, and which deck serves it.
+// A single-deck lift stops at the floor itself. A double-deck lift's Floor is
+// that of its lower deck, so its upper deck serves floor from the floor below.
+// Neither deck is left at a floor the lift doesn't serve if it can be helped.
+// It prefers a stop the lift is already at or making, so that one stop serves
+// both decks, and otherwise whichever is nearer. It must be called with
+// lift.mx held.
+func (lift *liftModel) stopFor(floor int) (int, Deck) {
+	if lift.Decks < 2 {
+		return floor, ""
+	}
+	below := floor - 1
+	switch {
+	case !lift.deckServes(below):
+		return floor, DeckLower
+	case !lift.deckServes(floor + 1):
+		// the upper deck would be left at a floor the lift doesn't serve
+		return below, DeckUpper
+	}
+
+	if lift.destination == nil {
+		switch lift.Floor {
+		case floor:
+			return floor, DeckLower
+		case below:
+			return below, DeckUpper
+		}
+	}
+	stops := lift.floorsToVisit.Values()
+	if lift.destination != nil {
+		stops = append(stops, *lift.destination)
+	}
+	switch {
+	case slices.Contains(stops, floor):
+		return floor, DeckLower
+	case slices.Contains(stops, below):
+		return below, DeckUpper
+	case abs(below-lift.Floor) < abs(floor-lift.Floor):
+		return below, DeckUpper
+	default:
+		return floor, DeckLower
+	}
+}
@@ -0,0 +1,106 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_DoubleDeck(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *LiftService, <-chan LiftEvent) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		t.Cleanup(func() { subs.Unsubscribe(id) })
+		return ctx, svc, ch
+	}
+
+	t.Run("a lift has one or two decks", func(t *testing.T) {
+		ctx, svc, _ := setup(t)
+		if _, err := svc.AddLift(ctx, LiftConfig{Decks: 3}); !errors.Is(err, ErrInvalidDecks) {
+			t.Errorf("expected ErrInvalidDecks, got %v", err)
+		}
+		if _, err := svc.AddLift(ctx, LiftConfig{Decks: 2, Shaft: "a"}); !errors.Is(err, ErrShaftDecks) {
+			t.Errorf("expected ErrShaftDecks, got %v", err)
+		}
+	})
+
+	t.Run("both decks serve adjacent floors at one stop", func(t *testing.T) {
+		ctx, svc, ch := setup(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 20, Decks: 2})
+
+		upper, _ := svc.CallLift(ctx, l.Id, 5)
+		lower, _ := svc.CallLift(ctx, l.Id, 4)
+		upperCall, _ := upper.Wait(ctx)
+		lowerCall, _ := lower.Wait(ctx)
+
+		if upperCall.Deck != DeckUpper || lowerCall.Deck != DeckLower {
+			t.Errorf("expected floor 5 on the upper deck and 4 on the lower, got %s and %s", upperCall.Deck, lowerCall.Deck)
+		}
+		got, _ := svc.GetLift(ctx, l.Id)
+		if got.Floor != 4 || got.Stops != 1 {
+			t.Errorf("expected one stop with the lower deck at floor 4, got floor %d after %d stops", got.Floor, got.Stops)
+		}
+		served := nextEvent(t, ch, "lift_call_served").Data.(LiftCallServed)
+		if served.Floor != 5 || served.Deck != DeckUpper {
+			t.Errorf("expected the upper deck to serve floor 5, got %+v", served)
+		}
+	})
+
+	t.Run("only the lower deck serves the lowest floor", func(t *testing.T) {
+		ctx, svc, _ := setup(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 3, Decks: 2})
+
+		handle, _ := svc.CallLift(ctx, l.Id, 0)
+		if call, _ := handle.Wait(ctx); call.Deck != DeckLower {
+			t.Errorf("expected the lower deck, got %s", call.Deck)
+		}
+		if got, _ := svc.GetLift(ctx, l.Id); got.Floor != 0 {
+			t.Errorf("expected the lift at floor 0, got %d", got.Floor)
+		}
+	})
+
+	t.Run("the decks serve a building's basements", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub(), WithLowestFloor(-2))
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: -2, Decks: 2})
+
+		// stopping at -1 for the upper deck is nearer than stopping at 0
+		handle, _ := svc.CallLift(ctx, l.Id, 0)
+		if call, _ := handle.Wait(ctx); call.Deck != DeckUpper {
+			t.Errorf("expected the upper deck to serve 0, got %s", call.Deck)
+		}
+		handle, _ = svc.CallLift(ctx, l.Id, -2)
+		if call, _ := handle.Wait(ctx); call.Deck != DeckLower {
+			t.Errorf("expected the lower deck to serve -2, got %s", call.Deck)
+		}
+	})
+
+	t.Run("neither deck stops at a floor the lift doesn't serve", func(t *testing.T) {
+		ctx, svc, _ := setup(t)
+		served := []FloorRange{{From: 0, To: 0}, {From: 10, To: 19}}
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, Decks: 2, ServedFloors: served})
+
+		// the upper deck serving 10 would leave the lower deck at 9
+		handle, _ := svc.CallLift(ctx, l.Id, 10)
+		if call, _ := handle.Wait(ctx); call.Deck != DeckLower {
+			t.Errorf("expected the lower deck to serve 10, got %s", call.Deck)
+		}
+
+		l, _ = svc.AddLift(ctx, LiftConfig{Floor: 20, Decks: 2, ServedFloors: []FloorRange{{From: 0, To: 9}, {From: 20, To: 20}}})
+		// the lower deck serving 9 would leave the upper deck at 10
+		handle, _ = svc.CallLift(ctx, l.Id, 9)
+		if call, _ := handle.Wait(ctx); call.Deck != DeckUpper {
+			t.Errorf("expected the upper deck to serve 9, got %s", call.Deck)
+		}
+		if got, _ := svc.GetLift(ctx, l.Id); got.Floor != 8 {
+			t.Errorf("expected the lower deck at floor 8, got %d", got.Floor)
+		}
+	})
+}
@@ -0,0 +1,59 @@
+package lift
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrNoLiftAvailable = errors.New("no lift available")
+
+// Dispatch assigns a hall call at floor to the nearest lift in service that
+// serves floor.
+func (svc *LiftService) Dispatch(ctx context.Context, floor int) (*CallHandle, error) {
+	requestedAt := svc.clock.Now()
+	if _, active := svc.EmergencyRecall(); active {
+		return nil, ErrEmergencyRecall
+	}
+	model, err := svc.nearestAvailableLift(floor)
+	if err != nil {
+		return nil, err
+	}
+	return model.call(ctx, liftCall{floor: floor, handle: newCallHandle(floor, requestedAt)})
+}
+
+func (svc *LiftService) nearestAvailableLift(floor int) (*liftModel, error) {
+	svc.mx.Lock()
+	defer svc.mx.Unlock()
+	var nearest *liftModel
+	var full error
+	bestDistance := -1
+	for _, id := range svc.liftOrder {
+		model, ok := svc.lifts[id]
+		if !ok || !model.available() || !model.reaches(floor) {
+			continue
+		}
+		if err := model.acceptsStop(floor); err != nil {
+			full = err
+			continue
+		}
+		distance := abs(model.currentFloor() - floor)
+		if nearest == nil || distance < bestDistance {
+			nearest = model
+			bestDistance = distance
+		}
+	}
+	switch {
+	case nearest != nil:
+		return nearest, nil
+	case full != nil:
+		return nil, full
+	}
+	return nil, ErrNoLiftAvailable
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,57 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Dispatch(t *testing.T) {
+	t.Run("assigns the nearest lift", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		svc.AddLift(ctx, LiftConfig{Floor: 0})
+		near, _ := svc.AddLift(ctx, LiftConfig{Floor: 8})
+
+		got, err := svc.Dispatch(ctx, 6)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Call().LiftId != near.Id {
+			t.Errorf("expected %s, got %s", near.Id, got.Call().LiftId)
+		}
+	})
+
+	t.Run("skips lifts that are out of service", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		far, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		near, _ := svc.AddLift(ctx, LiftConfig{Floor: 8})
+		svc.TakeOutOfService(ctx, near.Id, FinishTrip)
+
+		got, err := svc.Dispatch(ctx, 6)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Call().LiftId != far.Id {
+			t.Errorf("expected %s, got %s", far.Id, got.Call().LiftId)
+		}
+	})
+
+	t.Run("returns an error when no lift is available", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+
+		_, err := svc.Dispatch(ctx, 6)
+		if !errors.Is(err, ErrNoLiftAvailable) {
+			t.Errorf("expected no lift available error, got %v", err)
+		}
+	})
+}
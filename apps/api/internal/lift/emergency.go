@@ -0,0 +1,118 @@
+package lift
+
+import "context"
+
+// StartEmergencyRecall cancels every outstanding call and sends each lift in
+// service or in independent service to floor, where it waits with its doors
+// open. Calls are rejected with ErrEmergencyRecall until the recall is
+// cleared.
+func (svc *LiftService) StartEmergencyRecall(ctx context.Context, floor int) error {
+	svc.mx.Lock()
+	if svc.recallFloor != nil {
+		svc.mx.Unlock()
+		return ErrEmergencyRecall
+	}
+	svc.recallFloor = &floor
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	svc.publishEvent(createLiftEvent(LiftId{}, "emergency_recall_started", EmergencyRecallStarted{Floor: floor}))
+	for _, model := range models {
+		model.recall(ctx, floor)
+	}
+	return nil
+}
+
+// ClearEmergencyRecall closes the doors of every recalled lift and returns it
+// to the service it was in before the recall.
+func (svc *LiftService) ClearEmergencyRecall(ctx context.Context) error {
+	svc.mx.Lock()
+	if svc.recallFloor == nil {
+		svc.mx.Unlock()
+		return nil
+	}
+	svc.recallFloor = nil
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	svc.publishEvent(createLiftEvent(LiftId{}, "emergency_recall_cleared", EmergencyRecallCleared{}))
+	for _, model := range models {
+		model.clearRecall(ctx)
+	}
+	return nil
+}
+
+func (svc *LiftService) EmergencyRecall() (int, bool) {
+	svc.mx.Lock()
+	defer svc.mx.Unlock()
+	if svc.recallFloor == nil {
+		return 0, false
+	}
+	return *svc.recallFloor, true
+}
+
+// liftModels must be called with svc.mx held.
+func (svc *LiftService) liftModels() []*liftModel {
+	models := make([]*liftModel, 0, len(svc.liftOrder))
+	for _, id := range svc.liftOrder {
+		if model, ok := svc.lifts[id]; ok {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+func (lift *liftModel) recall(ctx context.Context, floor int) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status != StatusInService && lift.Status != StatusIndependent {
+		return
+	}
+	lift.recalledFrom = lift.Status
+	lift.cancelCalls(true)
+	lift.setStatus(ctx, StatusEmergency)
+	if lift.Car == CarUpper {
+		// the lower car in the shaft takes the recall floor itself
+		floor++
+	}
+	if lift.Floor == floor {
+		lift.openDoors(ctx)
+		return
+	}
+	// a lift in independent service may be holding its doors open
+	lift.closeDoors(ctx)
+	lift.floorsToVisit.Enqueue(floor)
+	lift.signalWake()
+}
+
+func (lift *liftModel) clearRecall(ctx context.Context) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status != StatusEmergency {
+		return
+	}
+	lift.cancelCalls(true)
+	if lift.recalledFrom == StatusIndependent {
+		// the doors are held open until the next car call, as on arrival
+		lift.setStatus(ctx, StatusIndependent)
+		return
+	}
+	lift.closeDoors(ctx)
+	lift.setStatus(ctx, StatusInService)
+}
+
+func (lift *liftModel) openDoors(ctx context.Context) {
+	if lift.DoorsOpen {
+		return
+	}
+	lift.DoorsOpen = true
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_doors_opened", LiftDoorsOpened{Floor: lift.Floor}))
+}
+
+func (lift *liftModel) closeDoors(ctx context.Context) {
+	if !lift.DoorsOpen {
+		return
+	}
+	lift.DoorsOpen = false
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_doors_closed", LiftDoorsClosed{Floor: lift.Floor}))
+}
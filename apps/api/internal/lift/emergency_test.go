@@ -0,0 +1,152 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_EmergencyRecall(t *testing.T) {
+	t.Run("recalled lifts travel to the recall floor and open their doors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 4})
+		if err := svc.StartEmergencyRecall(ctx, 0); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		ev := nextEvent(t, ch, "emergency_recall_started")
+		if ev.Data != (EmergencyRecallStarted{Floor: 0}) {
+			t.Errorf("expected recall to floor 0, got %v", ev.Data)
+		}
+		ev = nextEvent(t, ch, "lift_doors_opened")
+		if ev.LiftId != lift.Id || ev.Data != (LiftDoorsOpened{Floor: 0}) {
+			t.Errorf("expected doors to open at floor 0, got %v", ev)
+		}
+
+		got, _ := svc.GetLift(ctx, lift.Id)
+		if got.Status != StatusEmergency || !got.DoorsOpen {
+			t.Errorf("expected recalled lift with open doors, got %+v", got)
+		}
+	})
+
+	t.Run("calls are rejected during a recall", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.StartEmergencyRecall(ctx, 0)
+
+		if _, err := svc.CallLift(ctx, lift.Id, 3); !errors.Is(err, ErrEmergencyRecall) {
+			t.Errorf("expected emergency recall error, got %v", err)
+		}
+		if _, err := svc.Dispatch(ctx, 3); !errors.Is(err, ErrEmergencyRecall) {
+			t.Errorf("expected emergency recall error, got %v", err)
+		}
+	})
+
+	t.Run("a recall overrides queued floors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 5, FloorDelayMs: 20})
+		svc.CallLift(ctx, lift.Id, 10)
+		svc.CallLift(ctx, lift.Id, 12)
+		nextEvent(t, ch, "lift_transited")
+		svc.StartEmergencyRecall(ctx, 1)
+
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 1}) {
+			t.Errorf("expected arrival at recall floor, got %v", ev.Data)
+		}
+	})
+
+	t.Run("clearing a recall returns lifts to service", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.StartEmergencyRecall(ctx, 0)
+		nextEvent(t, ch, "lift_doors_opened")
+		svc.ClearEmergencyRecall(ctx)
+
+		nextEvent(t, ch, "emergency_recall_cleared")
+		nextEvent(t, ch, "lift_doors_closed")
+		ev := nextEvent(t, ch, "lift_status_changed")
+		if ev.Data != (LiftStatusChanged{Status: StatusInService}) {
+			t.Errorf("expected lift back in service, got %v", ev.Data)
+		}
+		if _, err := svc.CallLift(ctx, lift.Id, 3); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("lifts in independent service are recalled and returned to it", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 3, FloorDelayMs: 5})
+		svc.StartIndependentService(ctx, lift.Id)
+		nextEvent(t, ch, "lift_doors_opened")
+		svc.StartEmergencyRecall(ctx, 0)
+
+		nextEvent(t, ch, "lift_doors_closed")
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 0}) {
+			t.Errorf("expected arrival at the recall floor, got %v", ev.Data)
+		}
+		nextEvent(t, ch, "lift_doors_opened")
+		if got, _ := svc.GetLift(ctx, lift.Id); got.Status != StatusEmergency {
+			t.Errorf("expected %s, got %s", StatusEmergency, got.Status)
+		}
+
+		svc.ClearEmergencyRecall(ctx)
+		ev = nextEvent(t, ch, "lift_status_changed")
+		if ev.Data != (LiftStatusChanged{Status: StatusIndependent}) {
+			t.Errorf("expected lift back in independent service, got %v", ev.Data)
+		}
+		if got, _ := svc.GetLift(ctx, lift.Id); !got.DoorsOpen {
+			t.Errorf("expected doors held open, got %+v", got)
+		}
+		if _, err := svc.CallLift(ctx, lift.Id, 3); !errors.Is(err, ErrIndependentService) {
+			t.Errorf("expected independent service error, got %v", err)
+		}
+	})
+
+	t.Run("lifts out of service are not recalled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 3})
+		svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+		svc.StartEmergencyRecall(ctx, 0)
+
+		got, _ := svc.GetLift(ctx, lift.Id)
+		if got.Status != StatusOutOfService {
+			t.Errorf("expected %s, got %s", StatusOutOfService, got.Status)
+		}
+	})
+}
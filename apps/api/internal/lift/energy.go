@@ -0,0 +1,71 @@
+package lift
+
+import "context"
+
+// EnergyModel prices the work a lift does. Every floor travelled costs
+// FloorWh and every stop after moving costs StopWh, covering acceleration
+// and braking.
+//
+// Load is optional. Passengers aboard, counted by their outstanding car
+// calls, each weigh PassengerKg, and the counterweight balances the car with
+// BalanceKg of load. Moving the imbalance one floor costs WhPerKgFloor per
+// kilogram; when the imbalance drives the car instead, such as a full car
+// descending, Regeneration is the fraction of that energy recovered.
+type EnergyModel struct {
+	FloorWh      float64
+	StopWh       float64
+	PassengerKg  float64
+	BalanceKg    float64
+	WhPerKgFloor float64
+	Regeneration float64
+}
+
+// DefaultEnergyModel charges for distance and stops but ignores load.
+var DefaultEnergyModel = EnergyModel{FloorWh: 5, StopWh: 15}
+
+// WithEnergyModel sets how every lift's energy use is calculated.
+func WithEnergyModel(m EnergyModel) Option {
+	return func(svc *LiftService) {
+		svc.energy = m
+	}
+}
+
+// floor is the energy used travelling one floor in direction (1 for up, -1
+// for down) carrying passengers.
+func (m EnergyModel) floor(direction, passengers int) float64 {
+	imbalanceKg := float64(passengers)*m.PassengerKg - m.BalanceKg
+	work := imbalanceKg * m.WhPerKgFloor * float64(direction)
+	if work < 0 {
+		work *= m.Regeneration
+	}
+	return m.FloorWh + work
+}
+
+// The following helpers must be called with lift.mx held.
+
+func (lift *liftModel) passengers() int {
+	n := 0
+	for _, handles := range lift.pendingCalls {
+		for _, handle := range handles {
+			if handle.call.CarCall {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (lift *liftModel) chargeEnergy(wh float64) {
+	lift.tripWh += wh
+	lift.EnergyWh += wh
+}
+
+// reportEnergy publishes the energy used since the lift last stopped.
+func (lift *liftModel) reportEnergy(ctx context.Context) {
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_energy", LiftEnergy{
+		Floor:   lift.Floor,
+		TripWh:  lift.tripWh,
+		TotalWh: lift.EnergyWh,
+	}))
+	lift.tripWh = 0
+}
@@ -0,0 +1,62 @@
+package lift
+
+import (
+	"context"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Energy(t *testing.T) {
+	model := EnergyModel{FloorWh: 1, StopWh: 2, PassengerKg: 100, BalanceKg: 50, WhPerKgFloor: 0.1, Regeneration: 0.5}
+
+	t.Run("a heavy car costs more going up and regenerates going down", func(t *testing.T) {
+		if got := model.floor(1, 1); got != 6 {
+			t.Errorf("expected 6Wh up, got %v", got)
+		}
+		if got := model.floor(-1, 1); got != -1.5 {
+			t.Errorf("expected -1.5Wh down, got %v", got)
+		}
+		// an empty car is lighter than its counterweight
+		if got := model.floor(-1, 0); got != 6 {
+			t.Errorf("expected 6Wh down when empty, got %v", got)
+		}
+	})
+
+	t.Run("load is ignored by default", func(t *testing.T) {
+		if got := DefaultEnergyModel.floor(1, 10); got != DefaultEnergyModel.FloorWh {
+			t.Errorf("expected %vWh, got %v", DefaultEnergyModel.FloorWh, got)
+		}
+	})
+
+	t.Run("energy is reported on arrival and totalled on the lift", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps, WithEnergyModel(model))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+
+		// one passenger rides up two floors, then another rides back down
+		handle, _ := svc.CarCall(ctx, l.Id, 2)
+		up := nextEvent(t, ch, "lift_energy").Data.(LiftEnergy)
+		handle.Wait(ctx)
+		svc.CarCall(ctx, l.Id, 0)
+		down := nextEvent(t, ch, "lift_energy").Data.(LiftEnergy)
+
+		if up != (LiftEnergy{Floor: 2, TripWh: 14, TotalWh: 14}) {
+			t.Errorf("expected 14Wh going up, got %+v", up)
+		}
+		if down != (LiftEnergy{Floor: 0, TripWh: -1, TotalWh: 13}) {
+			t.Errorf("expected -1Wh coming down, got %+v", down)
+		}
+		if got, _ := svc.GetLift(ctx, l.Id); got.EnergyWh != 13 {
+			t.Errorf("expected the lift to have used 13Wh, got %v", got.EnergyWh)
+		}
+		if stats, _ := svc.Stats(ctx); stats.TotalEnergyWh != 13 || stats.EnergyWh[l.Id] != 13 {
+			t.Errorf("expected stats to report 13Wh, got %+v", stats)
+		}
+	})
+}
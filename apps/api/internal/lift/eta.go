@@ -0,0 +1,120 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+var ErrNoEstimate = errors.New("arrival cannot be estimated")
+
+// EstimateArrival predicts how long a hall call to floor would take to be
+// answered by the lift, by playing out its pending stops from where it is now.
+func (svc *LiftService) EstimateArrival(_ context.Context, id LiftId, floor int) (time.Duration, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return 0, err
+	}
+	return model.estimateArrival(floor)
+}
+
+// EstimateBestArrival returns the lift in service that would answer a hall
+// call to floor soonest, and how long it would take.
+func (svc *LiftService) EstimateBestArrival(_ context.Context, floor int) (LiftId, time.Duration, error) {
+	if _, active := svc.EmergencyRecall(); active {
+		return LiftId{}, 0, ErrEmergencyRecall
+	}
+	svc.mx.Lock()
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	var best LiftId
+	bestEta := time.Duration(-1)
+	for _, model := range models {
+		if !model.available() {
+			continue
+		}
+		eta, err := model.estimateArrival(floor)
+		if err != nil {
+			continue
+		}
+		if bestEta < 0 || eta < bestEta {
+			best, bestEta = model.Id, eta
+		}
+	}
+	if bestEta < 0 {
+		return LiftId{}, 0, ErrNoLiftAvailable
+	}
+	return best, bestEta, nil
+}
+
+func (lift *liftModel) estimateArrival(floor int) (time.Duration, error) {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	if err := lift.acceptsCall(lift.Status, liftCall{floor: floor}); err != nil {
+		return 0, err
+	}
+	if lift.Retiring {
+		return 0, ErrLiftRetiring
+	}
+	if _, stuck := lift.faults[FaultStuck]; stuck {
+		return 0, ErrNoEstimate
+	}
+	if _, obstructed := lift.faults[FaultDoorObstruction]; obstructed {
+		return 0, ErrNoEstimate
+	}
+
+	floor, _ = lift.stopFor(floor)
+	pos, direction := lift.Floor, lift.direction
+	var destination *int
+	// a call cancels parking, so a parking lift is as good as idle
+	if lift.destination != nil && lift.parkingAt == nil {
+		d := *lift.destination
+		destination = &d
+	}
+	if destination == nil && pos == floor {
+		return 0, nil
+	}
+	pending := lift.floorsToVisit.Values()
+	if (destination == nil || *destination != floor) && !slices.Contains(pending, floor) {
+		pending = append(pending, floor)
+	}
+
+	delay, dwell := lift.floorDelay(), lift.dwell()
+	elapsed := max(lift.busyUntil.Sub(lift.clock.Now()), 0)
+	for {
+		if destination == nil {
+			next := lift.scheduler.next(pending, pos, direction)
+			pending = remove(pending, next)
+			destination = &next
+		}
+		for pos != *destination {
+			direction = 1
+			if *destination < pos {
+				direction = -1
+			}
+			pos += direction
+			elapsed += delay
+			if pos != *destination && lift.scheduler.stopsOnTheWay() && slices.Contains(pending, pos) {
+				if pos == floor {
+					return elapsed, nil
+				}
+				pending = remove(pending, pos)
+				elapsed += dwell
+			}
+		}
+		if *destination == floor {
+			return elapsed, nil
+		}
+		elapsed += dwell
+		destination = nil
+	}
+}
+
+func remove(floors []int, floor int) []int {
+	if i := slices.Index(floors, floor); i >= 0 {
+		return slices.Delete(floors, i, i+1)
+	}
+	return floors
+}
@@ -0,0 +1,129 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_EstimateArrival(t *testing.T) {
+	newService := func(t *testing.T) (context.Context, *LiftService) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return ctx, NewLiftService(ctx, pubsub.NewMemoryPubSub(), WithClock(clock.NewVirtual(time.Now())))
+	}
+
+	// busyWith calls the lift to each floor and waits until it has set off
+	// for the first and queued the rest. The virtual clock is never advanced,
+	// so the lift stays one floor into its trip.
+	busyWith := func(t *testing.T, ctx context.Context, svc *LiftService, id LiftId, floors ...int) {
+		t.Helper()
+		for _, floor := range floors {
+			if _, err := svc.CallLift(ctx, id, floor); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		model, _ := svc.getLiftModel(id)
+		moving := func() bool {
+			model.mx.RLock()
+			defer model.mx.RUnlock()
+			return model.moving
+		}
+		timeout := time.After(time.Second)
+		for model.pendingStops() != len(floors) || !moving() {
+			select {
+			case <-timeout:
+				t.Fatal("timed out waiting for the lift to set off")
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+
+	t.Run("an idle lift travels straight to the floor", func(t *testing.T) {
+		ctx, svc := newService(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000, DoorDwellMs: 500})
+
+		eta, err := svc.EstimateArrival(ctx, l.Id, 5)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if eta != 5*time.Second {
+			t.Errorf("expected 5s, got %s", eta)
+		}
+		if eta, _ := svc.EstimateArrival(ctx, l.Id, 0); eta != 0 {
+			t.Errorf("expected no wait at the lift's own floor, got %s", eta)
+		}
+	})
+
+	t.Run("pending stops and dwell time are played out first", func(t *testing.T) {
+		ctx, svc := newService(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000, DoorDwellMs: 500})
+		busyWith(t, ctx, svc, l.Id, 5, 2)
+
+		// 1s to finish reaching floor 1, 4s on to 5, 3s back to 2 and 6s up
+		// to 8, dwelling at 5 and 2 on the way.
+		eta, err := svc.EstimateArrival(ctx, l.Id, 8)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want := 15 * time.Second; eta != want {
+			t.Errorf("expected %s, got %s", want, eta)
+		}
+	})
+
+	t.Run("stops on the way count for schedulers that make them", func(t *testing.T) {
+		ctx, svc := newService(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000, DoorDwellMs: 500, Scheduler: SchedulerScan})
+		busyWith(t, ctx, svc, l.Id, 5, 2)
+
+		eta, err := svc.EstimateArrival(ctx, l.Id, 3)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if want := 3500 * time.Millisecond; eta != want {
+			t.Errorf("expected %s, got %s", want, eta)
+		}
+	})
+
+	t.Run("lifts that cannot answer calls have no estimate", func(t *testing.T) {
+		ctx, svc := newService(t)
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+
+		if _, err := svc.EstimateArrival(ctx, NewLiftId(), 1); !errors.Is(err, ErrLiftNotFound) {
+			t.Errorf("expected lift not found error, got %v", err)
+		}
+		svc.InjectFault(ctx, l.Id, Fault{Type: FaultStuck})
+		if _, err := svc.EstimateArrival(ctx, l.Id, 1); !errors.Is(err, ErrNoEstimate) {
+			t.Errorf("expected no estimate error, got %v", err)
+		}
+		svc.ClearFault(ctx, l.Id, FaultStuck)
+		svc.TakeOutOfService(ctx, l.Id, FinishTrip)
+		if _, err := svc.EstimateArrival(ctx, l.Id, 1); !errors.Is(err, ErrLiftOutOfService) {
+			t.Errorf("expected out of service error, got %v", err)
+		}
+		if _, _, err := svc.EstimateBestArrival(ctx, 1); !errors.Is(err, ErrNoLiftAvailable) {
+			t.Errorf("expected no lift available error, got %v", err)
+		}
+	})
+
+	t.Run("the best lift is the one that would arrive soonest", func(t *testing.T) {
+		ctx, svc := newService(t)
+		svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000})
+		far, _ := svc.AddLift(ctx, LiftConfig{Floor: 10, FloorDelayMs: 1000})
+		busy, _ := svc.AddLift(ctx, LiftConfig{Floor: 8, FloorDelayMs: 1000})
+		busyWith(t, ctx, svc, busy.Id, 0)
+
+		id, eta, err := svc.EstimateBestArrival(ctx, 8)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if id != far.Id || eta != 2*time.Second {
+			t.Errorf("expected lift %s in 2s, got %s in %s", far.Id, id, eta)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+type FaultType string
+
+const (
+	// FaultStuck halts the lift wherever it is, including between floors.
+	FaultStuck FaultType = "stuck"
+	// FaultDoorObstruction stops the lift from leaving the floor it is stopped at.
+	FaultDoorObstruction FaultType = "door_obstruction"
+	// FaultSlowMotor scales the time taken to travel between floors.
+	FaultSlowMotor FaultType = "slow_motor"
+	// FaultDroppedCalls causes calls to be accepted but never served.
+	FaultDroppedCalls FaultType = "dropped_calls"
+)
+
+var ErrUnknownFault = errors.New("unknown fault")
+
+type Fault struct {
+	Type FaultType
+	// SlowdownFactor multiplies the floor delay of a slow motor. Defaults to 2.
+	SlowdownFactor float64
+	// DropRate is the proportion of calls lost, between 0 and 1. Defaults to 1.
+	DropRate float64
+}
+
+func (f Fault) withDefaults() (Fault, error) {
+	switch f.Type {
+	case FaultStuck, FaultDoorObstruction:
+	case FaultSlowMotor:
+		if f.SlowdownFactor <= 1 {
+			f.SlowdownFactor = 2
+		}
+	case FaultDroppedCalls:
+		if f.DropRate <= 0 || f.DropRate > 1 {
+			f.DropRate = 1
+		}
+	default:
+		return f, ErrUnknownFault
+	}
+	return f, nil
+}
+
+// InjectFault makes a lift misbehave until the fault is cleared. Faulted lifts
+// are never chosen by Dispatch.
+func (svc *LiftService) InjectFault(ctx context.Context, id LiftId, fault Fault) (Lift, error) {
+	fault, err := fault.withDefaults()
+	if err != nil {
+		return Lift{}, err
+	}
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	model.raiseFault(ctx, fault)
+	return model.snapshot(), nil
+}
+
+func (svc *LiftService) ClearFault(ctx context.Context, id LiftId, faultType FaultType) (Lift, error) {
+	if _, err := (Fault{Type: faultType}).withDefaults(); err != nil {
+		return Lift{}, err
+	}
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	model.clearFault(ctx, faultType)
+	return model.snapshot(), nil
+}
+
+func (lift *liftModel) raiseFault(ctx context.Context, fault Fault) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	_, exists := lift.faults[fault.Type]
+	lift.faults[fault.Type] = fault
+	if !exists {
+		lift.publish(ctx, createLiftEvent(lift.Id, "lift_fault_raised", LiftFaultRaised{Fault: fault.Type, Floor: lift.Floor}))
+	}
+}
+
+func (lift *liftModel) clearFault(ctx context.Context, faultType FaultType) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if _, ok := lift.faults[faultType]; !ok {
+		return
+	}
+	delete(lift.faults, faultType)
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_fault_cleared", LiftFaultCleared{Fault: faultType, Floor: lift.Floor}))
+	select {
+	case lift.resume <- struct{}{}:
+	default:
+	}
+}
+
+// The following helpers must be called with lift.mx held.
+
+func (lift *liftModel) activeFaults() []FaultType {
+	if len(lift.faults) == 0 {
+		return nil
+	}
+	faults := make([]FaultType, 0, len(lift.faults))
+	for faultType := range lift.faults {
+		faults = append(faults, faultType)
+	}
+	sort.Slice(faults, func(i, j int) bool { return faults[i] < faults[j] })
+	return faults
+}
+
+func (lift *liftModel) immobilised() bool {
+	if _, stuck := lift.faults[FaultStuck]; stuck {
+		return true
+	}
+	_, obstructed := lift.faults[FaultDoorObstruction]
+	return obstructed && !lift.moving
+}
+
+func (lift *liftModel) floorDelay() time.Duration {
+	delay := time.Duration(lift.floorDelayMs) * time.Millisecond
+	if fault, ok := lift.faults[FaultSlowMotor]; ok {
+		delay = time.Duration(float64(delay) * fault.SlowdownFactor)
+	}
+	return delay
+}
+
+func (lift *liftModel) dropsCall() bool {
+	fault, ok := lift.faults[FaultDroppedCalls]
+	return ok && rand.Float64() < fault.DropRate
+}
@@ -0,0 +1,121 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Faults(t *testing.T) {
+	t.Run("a stuck lift stops until the fault is cleared", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 20})
+		svc.CallLift(ctx, lift.Id, 5)
+		nextEvent(t, ch, "lift_transited")
+		got, err := svc.InjectFault(ctx, lift.Id, Fault{Type: FaultStuck})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(got.Faults) != 1 || got.Faults[0] != FaultStuck {
+			t.Errorf("expected stuck fault, got %v", got.Faults)
+		}
+		nextEvent(t, ch, "lift_fault_raised")
+
+		select {
+		case ev := <-ch:
+			t.Errorf("expected no events while stuck, got %v", ev)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		svc.ClearFault(ctx, lift.Id, FaultStuck)
+		nextEvent(t, ch, "lift_fault_cleared")
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 5}) {
+			t.Errorf("expected arrival at 5, got %v", ev.Data)
+		}
+	})
+
+	t.Run("a door obstruction prevents the lift departing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 2})
+		svc.InjectFault(ctx, lift.Id, Fault{Type: FaultDoorObstruction})
+		svc.CallLift(ctx, lift.Id, 4)
+
+		time.Sleep(50 * time.Millisecond)
+		if got, _ := svc.GetLift(ctx, lift.Id); got.Floor != 2 {
+			t.Errorf("expected lift to remain at 2, got %d", got.Floor)
+		}
+	})
+
+	t.Run("a slow motor scales the floor delay", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 10})
+		svc.InjectFault(ctx, lift.Id, Fault{Type: FaultSlowMotor, SlowdownFactor: 3})
+
+		model, _ := svc.getLiftModel(lift.Id)
+		model.mx.RLock()
+		delay := model.floorDelay()
+		model.mx.RUnlock()
+		if delay != 30*time.Millisecond {
+			t.Errorf("expected 30ms, got %s", delay)
+		}
+	})
+
+	t.Run("dropped calls are never served", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.InjectFault(ctx, lift.Id, Fault{Type: FaultDroppedCalls})
+
+		if _, err := svc.CallLift(ctx, lift.Id, 3); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+		if got, _ := svc.GetLift(ctx, lift.Id); got.Floor != 0 {
+			t.Errorf("expected lift to remain at 0, got %d", got.Floor)
+		}
+	})
+
+	t.Run("faulted lifts are excluded from dispatch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		far, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		near, _ := svc.AddLift(ctx, LiftConfig{Floor: 5})
+		svc.InjectFault(ctx, near.Id, Fault{Type: FaultSlowMotor})
+
+		got, err := svc.Dispatch(ctx, 5)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Call().LiftId != far.Id {
+			t.Errorf("expected %s, got %s", far.Id, got.Call().LiftId)
+		}
+	})
+
+	t.Run("unknown faults are rejected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+
+		if _, err := svc.InjectFault(ctx, lift.Id, Fault{Type: "gremlins"}); !errors.Is(err, ErrUnknownFault) {
+			t.Errorf("expected unknown fault error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package lift
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrTooManyCalls = errors.New("too many calls")
+
+// TooManyCallsError is returned when a lift is called to a new floor while it
+// already has as many stops pending as it allows. It matches ErrTooManyCalls.
+type TooManyCallsError struct {
+	LiftId LiftId
+	Limit  int
+	// RetryAfter is roughly when the lift will have made its next stop.
+	RetryAfter time.Duration
+}
+
+func (e *TooManyCallsError) Error() string {
+	return fmt.Sprintf("lift %s already has %d stops pending", e.LiftId, e.Limit)
+}
+
+func (e *TooManyCallsError) Is(target error) bool {
+	return target == ErrTooManyCalls
+}
+
+// WithMaxPendingStops caps how many floors each lift can have left to visit,
+// including the one it is heading for, so a flood of calls can't queue up
+// work without end. Calls to a floor the lift is already stopping at are
+// always accepted. Zero means no cap.
+func WithMaxPendingStops(n int) Option {
+	return func(svc *LiftService) {
+		svc.maxStops = n
+	}
+}
+
+func (lift *liftModel) acceptsStop(floor int) error {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	return lift.checkStops(floor)
+}
+
+// reserveStop checks the lift has room for a stop at floor, and holds it for
+// a call on its way to handleCalls so that calls made at the same time can't
+// overfill the lift. Calls to a retiring lift are rejected. The stop is
+// released by addFloorToVisit, or by releaseStop if the call never gets
+// there.
+func (lift *liftModel) reserveStop(floor int) error {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Retiring {
+		return ErrLiftRetiring
+	}
+	if err := lift.checkStops(floor); err != nil {
+		return err
+	}
+	lift.reserved = append(lift.reserved, floor)
+	return nil
+}
+
+func (lift *liftModel) releaseStop(floor int) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	lift.unreserve(floor)
+}
+
+// unreserve must be called with lift.mx held.
+func (lift *liftModel) unreserve(floor int) {
+	if i := slices.Index(lift.reserved, floor); i >= 0 {
+		lift.reserved = slices.Delete(lift.reserved, i, i+1)
+	}
+	if lift.Retiring {
+		// the call may have been all the lift was waiting for
+		lift.signalWake()
+	}
+}
+
+// stops is every floor the lift has yet to stop at, including those reserved
+// for calls on their way to it. It must be called with lift.mx held.
+func (lift *liftModel) stops() map[int]bool {
+	stops := make(map[int]bool)
+	for _, floor := range lift.floorsToVisit.Values() {
+		stops[floor] = true
+	}
+	if lift.destination != nil && lift.parkingAt == nil {
+		stops[*lift.destination] = true
+	}
+	for _, floor := range lift.reserved {
+		floor, _ = lift.stopFor(floor)
+		stops[floor] = true
+	}
+	return stops
+}
+
+// checkStops must be called with lift.mx held.
+func (lift *liftModel) checkStops(floor int) error {
+	if lift.maxStops <= 0 {
+		return nil
+	}
+	stops := lift.stops()
+	if len(stops) < lift.maxStops {
+		return nil
+	}
+	floor, _ = lift.stopFor(floor)
+	if stops[floor] || (lift.destination != nil && *lift.destination == floor) {
+		return nil
+	}
+	next := lift.Floor
+	if lift.destination != nil {
+		next = *lift.destination
+	} else if stops := lift.floorsToVisit.Values(); len(stops) > 0 {
+		next = stops[0]
+	} else if len(lift.reserved) > 0 {
+		next, _ = lift.stopFor(lift.reserved[0])
+	}
+	retryAfter := max(0, lift.busyUntil.Sub(lift.clock.Now())) +
+		time.Duration(abs(next-lift.Floor)*lift.floorDelayMs)*time.Millisecond + lift.dwell()
+	return &TooManyCallsError{LiftId: lift.Id, Limit: lift.maxStops, RetryAfter: retryAfter}
+}
@@ -0,0 +1,75 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_MaxPendingStops(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *LiftService, Lift) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub(), WithMaxPendingStops(2))
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 60_000})
+		return ctx, svc, l
+	}
+	// flood calls the lift to one floor after another until it refuses.
+	flood := func(t *testing.T, ctx context.Context, svc *LiftService, id LiftId) error {
+		for floor := 1; floor <= 20; floor++ {
+			if _, err := svc.CallLift(ctx, id, floor); err != nil {
+				return err
+			}
+		}
+		t.Fatal("expected the lift to refuse calls once it had too many stops")
+		return nil
+	}
+
+	t.Run("calls to new floors are refused once the lift is full", func(t *testing.T) {
+		ctx, svc, l := setup(t)
+		err := flood(t, ctx, svc, l.Id)
+		var tooMany *TooManyCallsError
+		if !errors.As(err, &tooMany) || tooMany.LiftId != l.Id || tooMany.Limit != 2 || tooMany.RetryAfter <= 0 {
+			t.Errorf("expected TooManyCallsError, got %v", err)
+		}
+		if _, err := svc.CallLift(ctx, l.Id, 1); err != nil {
+			t.Errorf("expected a call to a floor already pending to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("dispatch refuses calls when every lift is full", func(t *testing.T) {
+		ctx, svc, l := setup(t)
+		flood(t, ctx, svc, l.Id)
+		if _, err := svc.Dispatch(ctx, 15); !errors.Is(err, ErrTooManyCalls) {
+			t.Errorf("expected ErrTooManyCalls, got %v", err)
+		}
+		other, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		handle, err := svc.Dispatch(ctx, 15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if call, _ := handle.Wait(ctx); call.LiftId != other.Id {
+			t.Errorf("expected the lift with room to answer, got %s", call.LiftId)
+		}
+	})
+	t.Run("stops held for calls on their way to the lift count", func(t *testing.T) {
+		ctx, svc, l := setup(t)
+		model, _ := svc.getLiftModel(l.Id)
+		// as if two calls had been accepted but not yet reached the lift
+		model.reserveStop(1)
+		model.reserveStop(2)
+
+		if _, err := svc.CallLift(ctx, l.Id, 3); !errors.Is(err, ErrTooManyCalls) {
+			t.Errorf("expected ErrTooManyCalls, got %v", err)
+		}
+		if _, err := svc.CallLift(ctx, l.Id, 2); err != nil {
+			t.Errorf("expected a call to a floor already held to be accepted, got %v", err)
+		}
+		model.releaseStop(1)
+		if _, err := svc.CallLift(ctx, l.Id, 3); err != nil {
+			t.Errorf("expected a call once a stop was released to be accepted, got %v", err)
+		}
+	})
+}
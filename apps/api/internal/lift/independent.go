@@ -0,0 +1,67 @@
+package lift
+
+import "context"
+
+// StartIndependentService takes a lift out of group control so it can be
+// driven by car calls alone. Hall calls are rejected with ErrIndependentService
+// and the doors are held open at each floor until the next car call.
+func (svc *LiftService) StartIndependentService(ctx context.Context, id LiftId) (Lift, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	if _, active := svc.EmergencyRecall(); active {
+		return Lift{}, ErrEmergencyRecall
+	}
+	if err := model.startIndependentService(ctx); err != nil {
+		return Lift{}, err
+	}
+	return model.snapshot(), nil
+}
+
+func (svc *LiftService) EndIndependentService(ctx context.Context, id LiftId) (Lift, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	if model.endIndependentService(ctx) {
+		if floor, active := svc.EmergencyRecall(); active {
+			model.recall(ctx, floor)
+		}
+	}
+	return model.snapshot(), nil
+}
+
+func (lift *liftModel) startIndependentService(ctx context.Context) error {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	switch lift.Status {
+	case StatusIndependent:
+		return nil
+	case StatusOutOfService:
+		return ErrLiftOutOfService
+	}
+	lift.cancelCalls(false)
+	lift.setStatus(ctx, StatusIndependent)
+	if lift.destination == nil {
+		lift.openDoors(ctx)
+	}
+	return nil
+}
+
+func (lift *liftModel) endIndependentService(ctx context.Context) bool {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status == StatusEmergency && lift.recalledFrom == StatusIndependent {
+		// the lift returns to service, rather than independent service, once
+		// the recall is cleared
+		lift.recalledFrom = StatusInService
+		return false
+	}
+	if lift.Status != StatusIndependent {
+		return false
+	}
+	lift.closeDoors(ctx)
+	lift.setStatus(ctx, StatusInService)
+	return true
+}
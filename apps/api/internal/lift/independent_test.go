@@ -0,0 +1,95 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_IndependentService(t *testing.T) {
+	t.Run("hall calls are rejected and car calls are served", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 2})
+		got, err := svc.StartIndependentService(ctx, lift.Id)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Status != StatusIndependent || !got.DoorsOpen {
+			t.Errorf("expected independent lift with open doors, got %+v", got)
+		}
+
+		if _, err := svc.CallLift(ctx, lift.Id, 5); !errors.Is(err, ErrIndependentService) {
+			t.Errorf("expected independent service error, got %v", err)
+		}
+		if _, err := svc.CarCall(ctx, lift.Id, 5); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		nextEvent(t, ch, "lift_doors_closed")
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 5}) {
+			t.Errorf("expected arrival at 5, got %v", ev.Data)
+		}
+		ev = nextEvent(t, ch, "lift_doors_opened")
+		if ev.Data != (LiftDoorsOpened{Floor: 5}) {
+			t.Errorf("expected doors to open at 5, got %v", ev.Data)
+		}
+	})
+
+	t.Run("independent lifts are excluded from dispatch", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		far, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		near, _ := svc.AddLift(ctx, LiftConfig{Floor: 5})
+		svc.StartIndependentService(ctx, near.Id)
+
+		got, err := svc.Dispatch(ctx, 5)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Call().LiftId != far.Id {
+			t.Errorf("expected %s, got %s", far.Id, got.Call().LiftId)
+		}
+	})
+
+	t.Run("ending independent service returns the lift to group control", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.StartIndependentService(ctx, lift.Id)
+
+		got, err := svc.EndIndependentService(ctx, lift.Id)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Status != StatusInService || got.DoorsOpen {
+			t.Errorf("expected lift in service with closed doors, got %+v", got)
+		}
+		if _, err := svc.CallLift(ctx, lift.Id, 3); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("a lift out of service cannot enter independent service", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+
+		if _, err := svc.StartIndependentService(ctx, lift.Id); !errors.Is(err, ErrLiftOutOfService) {
+			t.Errorf("expected out of service error, got %v", err)
+		}
+	})
+}
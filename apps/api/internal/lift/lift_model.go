@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/leow93/miffed-api/internal/clock"
 	"github.com/leow93/miffed-api/internal/pubsub"
 	"github.com/leow93/miffed-api/internal/queue"
 )
@@ -15,31 +16,108 @@ import (
 type LiftConfig struct {
 	Floor        int
 	FloorDelayMs int
+	// DoorDwellMs is how long the lift waits at each stop before moving on.
+	DoorDwellMs int
+	Scheduler   Scheduler // defaults to SchedulerFIFO
+	// ServedFloors restricts the floors the lift stops at, such as a high
+	// rise bank or an express lift. It serves every floor if empty.
+	ServedFloors []FloorRange
+	// Decks is 2 for a double-deck lift, whose decks serve adjacent floors at
+	// the same stop. Defaults to 1.
+	Decks int
+	// Shaft names the shaft a lift shares with one other car, each moving
+	// independently. Lifts with no shaft have one to themselves.
+	Shaft string
 }
 
+type LiftStatus string
+
+const (
+	StatusInService    LiftStatus = "in_service"
+	StatusOutOfService LiftStatus = "out_of_service"
+	StatusEmergency    LiftStatus = "emergency_recall"
+	StatusIndependent  LiftStatus = "independent_service"
+)
+
 type Lift struct {
-	Id           LiftId
-	Floor        int
-	floorDelayMs int
+	Id LiftId
+	// Floor is where the car is, or its lower deck if it has two.
+	Floor  int
+	Status LiftStatus
+	// Retiring is set once the lift has been retired, until it has served
+	// its calls and is removed.
+	Retiring  bool
+	DoorsOpen bool
+	Faults    []FaultType
+	// FloorsTravelled, Stops and EnergyWh total how far the lift has moved, how
+	// many times it has stopped after moving and the energy it used doing so
+	// since it was added.
+	FloorsTravelled int
+	Stops           int
+	EnergyWh        float64
+	ServedFloors    []FloorRange
+	Decks           int
+	Shaft           string
+	Car             Car // which car the lift is in its shaft, if it shares one
+	floorDelayMs    int
+	doorDwellMs     int
+	scheduler       Scheduler
+}
+
+// liftCall is a request for the lift to visit a floor, either from a landing
+// (a hall call) or from a button inside the car (a car call).
+type liftCall struct {
+	floor   int
+	carCall bool
+	handle  *CallHandle
 }
 
 type liftModel struct {
 	Lift
 	floorsToVisit *queue.Queue
-	callsChan     chan int       // channel which buffers client calls
-	transitChan   chan int       // channel which takes valid floors to visit and moves there one by one
+	destination   *int // floor the lift is currently travelling to, nil when idle
+	moving        bool // whether the lift has left the floor it started its trip from
+	direction     int  // direction of the last floor travelled, -1 for down and 1 for up
+	faults        map[FaultType]Fault
+	pendingCalls  map[int][]*CallHandle // outstanding calls by floor
+	busyUntil     time.Time             // when the floor or stop the lift is part way through ends
+	energy        EnergyModel
+	tripWh        float64 // energy used since the lift last stopped
+	parking       ParkingConfig
+	parkingAt     *int // floor the lift is parking at, nil unless it is heading there
+	parkingFloor  func(LiftId) (int, bool)
+	maxStops      int    // pending stops the lift accepts, unlimited if 0
+	lowest        int    // the building's lowest floor
+	reserved      []int  // stops held for calls on their way to handleCalls
+	shaft         *shaft // nil unless the lift shares its shaft
+	clock         clock.Clock
+	metrics       *liftMetrics
+	stats         *callStats
+	recalledFrom  LiftStatus     // status the lift returns to when a recall is cleared
+	callsChan     chan liftCall  // channel which buffers client calls
+	wake          chan struct{}  // signalled whenever a floor is added to floorsToVisit
+	resume        chan struct{}  // signalled whenever a fault is cleared
 	notifications chan LiftEvent // channel for clients to receive notifications on
+	stopped       chan struct{}  // closed once notifications are no longer received
 	floorDelayMs  int
 	mx            sync.RWMutex
 }
 
-func newLiftModel(lift Lift) *liftModel {
+func newLiftModel(lift Lift, clock clock.Clock, energy EnergyModel, metrics *liftMetrics, stats *callStats) *liftModel {
 	return &liftModel{
 		Lift:          lift,
 		floorsToVisit: queue.NewQueue(),
-		callsChan:     make(chan int),
-		transitChan:   make(chan int),
+		callsChan:     make(chan liftCall),
+		wake:          make(chan struct{}, 1),
+		resume:        make(chan struct{}, 1),
+		faults:        make(map[FaultType]Fault),
+		pendingCalls:  make(map[int][]*CallHandle),
+		clock:         clock,
+		energy:        energy,
+		metrics:       metrics,
+		stats:         stats,
 		notifications: make(chan LiftEvent),
+		stopped:       make(chan struct{}),
 		floorDelayMs:  lift.floorDelayMs,
 		mx:            sync.RWMutex{},
 	}
@@ -51,90 +129,350 @@ func (lift *liftModel) currentFloor() int {
 	return lift.Floor
 }
 
-func (lift *liftModel) transitToFloor(ctx context.Context, delta int) {
+func (lift *liftModel) snapshot() Lift {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	return Lift{
+		Id:              lift.Id,
+		Floor:           lift.Floor,
+		Status:          lift.Status,
+		Retiring:        lift.Retiring,
+		DoorsOpen:       lift.DoorsOpen,
+		Faults:          lift.activeFaults(),
+		FloorsTravelled: lift.FloorsTravelled,
+		Stops:           lift.Stops,
+		EnergyWh:        lift.EnergyWh,
+		ServedFloors:    lift.ServedFloors,
+		Decks:           lift.Decks,
+		Shaft:           lift.Shaft,
+		Car:             lift.Car,
+	}
+}
+
+func (lift *liftModel) available() bool {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	return lift.Status == StatusInService && !lift.Retiring && len(lift.faults) == 0
+}
+
+// transitTowards moves the lift one floor closer to floor. It returns false once
+// the lift has arrived, or if floor is no longer the lift's destination.
+func (lift *liftModel) transitTowards(ctx context.Context, floor int) bool {
 	lift.mx.Lock()
-	defer lift.mx.Unlock()
+	if lift.destination == nil || *lift.destination != floor {
+		lift.shaft.release(lift)
+		lift.mx.Unlock()
+		return false
+	}
+	if lift.immobilised() {
+		lift.mx.Unlock()
+		select {
+		case <-ctx.Done():
+			return false
+		case <-lift.resume:
+			return true
+		}
+	}
+	target, changed := lift.shaft.target(lift, floor)
+	if lift.Floor == floor && target == floor {
+		lift.destination = nil
+		dwell := lift.arrive(ctx, floor)
+		lift.mx.Unlock()
+
+		lift.clock.Sleep(dwell)
+		return false
+	}
+	if lift.Floor == target {
+		// holding out of the way of the other car in the shaft
+		lift.mx.Unlock()
+		return waitForShaft(ctx, changed)
+	}
+	if lift.moving && lift.scheduler.stopsOnTheWay() && lift.floorsToVisit.Remove(lift.Floor) {
+		dwell := lift.arrive(ctx, lift.Floor)
+		lift.mx.Unlock()
+
+		lift.clock.Sleep(dwell)
+		return true
+	}
+
+	to := lift.Floor + 1
+	if lift.Floor > target {
+		to = lift.Floor - 1
+	}
+	if ok, changed := lift.shaft.move(lift, to, floor); !ok {
+		lift.mx.Unlock()
+		return waitForShaft(ctx, changed)
+	}
+	lift.moveTo(ctx, to)
+	return true
+}
+
+// moveTo travels one floor to to. It must be called with lift.mx held, which
+// it releases for the journey.
+func (lift *liftModel) moveTo(ctx context.Context, to int) {
 	from := lift.Floor
-	to := lift.Floor + delta
 	lift.Floor = to
+	lift.moving = true
+	lift.direction = to - from
+	lift.FloorsTravelled++
+	lift.chargeEnergy(lift.energy.floor(lift.direction, lift.passengers()))
 	lift.publish(ctx, createLiftEvent(lift.Id, "lift_transited", LiftTransited{From: from, To: to}))
-	time.Sleep(time.Duration(lift.floorDelayMs * 1000 * 1000))
+	lift.metrics.floorTravelled(lift.Id)
+	delay := lift.floorDelay()
+	lift.busyUntil = lift.clock.Now().Add(delay)
+	lift.mx.Unlock()
+
+	lift.clock.Sleep(delay)
+}
+
+// arrive stops the lift at floor and serves anyone waiting there, returning
+// how long the lift should dwell before moving on. It must be called with
+// lift.mx held.
+func (lift *liftModel) arrive(ctx context.Context, floor int) time.Duration {
+	lift.shaft.release(lift)
+	moved := lift.moving
+	if moved {
+		lift.Stops++
+		lift.chargeEnergy(lift.energy.StopWh)
+	}
+	lift.moving = false
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_arrived", LiftArrived{Floor: floor}))
+	if moved {
+		lift.reportEnergy(ctx)
+	}
+	lift.serveCalls(ctx, floor)
+	if lift.Status == StatusEmergency || lift.Status == StatusIndependent {
+		lift.openDoors(ctx)
+	}
+	if lift.parkingAt != nil {
+		// nobody is waiting for a parked lift, so it doesn't dwell
+		lift.parkingAt = nil
+		lift.busyUntil = lift.clock.Now()
+		return 0
+	}
+	dwell := lift.dwell()
+	lift.busyUntil = lift.clock.Now().Add(dwell)
+	return dwell
+}
+
+func (lift *liftModel) dwell() time.Duration {
+	return time.Duration(lift.doorDwellMs) * time.Millisecond
 }
 
-func (lift *liftModel) call(ctx context.Context, floor int) error {
+func (lift *liftModel) call(ctx context.Context, c liftCall) (*CallHandle, error) {
+	if err := lift.acceptsCall(lift.snapshot().Status, c); err != nil {
+		return nil, err
+	}
+	if err := lift.reserveStop(c.floor); err != nil {
+		return nil, err
+	}
+	c.handle.call.LiftId = lift.Id
+	c.handle.call.CarCall = c.carCall
+	c.handle.call.AssignedAt = lift.clock.Now()
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		lift.releaseStop(c.floor)
+		return nil, ctx.Err()
 	case <-time.After(time.Second):
-		return fmt.Errorf("timed out calling lift")
-	case lift.callsChan <- floor:
-		return nil
+		lift.releaseStop(c.floor)
+		return nil, fmt.Errorf("timed out calling lift")
+	case lift.callsChan <- c:
+		lift.metrics.callReceived(lift.Id)
+		return c.handle, nil
 	}
 }
 
+func (lift *liftModel) acceptsCall(status LiftStatus, c liftCall) error {
+	if !lift.reaches(c.floor) {
+		return &FloorNotServedError{LiftId: lift.Id, Floor: c.floor}
+	}
+	switch status {
+	case StatusOutOfService:
+		return ErrLiftOutOfService
+	case StatusEmergency:
+		return ErrEmergencyRecall
+	case StatusIndependent:
+		if !c.carCall {
+			return ErrIndependentService
+		}
+	}
+	return nil
+}
+
 func (lift *liftModel) publish(ctx context.Context, ev LiftEvent) {
+	ev.OccurredAt = lift.clock.Now()
 	select {
 	case <-ctx.Done():
 		return
+	case <-lift.stopped:
+		return
 	case lift.notifications <- ev:
 		return
 	}
 }
 
+func (lift *liftModel) addFloorToVisit(ctx context.Context, c liftCall) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	lift.unreserve(c.floor)
+	floor := c.floor
+	if lift.acceptsCall(lift.Status, c) != nil {
+		c.handle.cancel()
+		return
+	}
+	if lift.dropsCall() {
+		return
+	}
+	lift.cancelParking(ctx)
+	floor, c.handle.deck = lift.stopFor(floor)
+	if lift.destination == nil && lift.Floor == floor {
+		lift.serveCall(ctx, c.handle)
+		return
+	}
+	lift.closeDoors(ctx)
+	lift.pendingCalls[floor] = append(lift.pendingCalls[floor], c.handle)
+	if lift.destination != nil && *lift.destination == floor {
+		return
+	}
+	if !lift.floorsToVisit.Has(floor) {
+		lift.floorsToVisit.Enqueue(floor)
+	}
+	lift.signalWake()
+}
+
+// serveCalls must be called with lift.mx held.
+func (lift *liftModel) serveCalls(ctx context.Context, floor int) {
+	for _, call := range lift.pendingCalls[floor] {
+		lift.serveCall(ctx, call)
+	}
+	delete(lift.pendingCalls, floor)
+}
+
+func (lift *liftModel) serveCall(ctx context.Context, handle *CallHandle) {
+	call := handle.arrive(lift.clock.Now())
+	wait := call.WaitTime()
+	lift.metrics.callServed(lift.Id, call)
+	lift.stats.record(call)
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_call_served", LiftCallServed{
+		CallId: call.Id,
+		Floor:  call.Floor,
+		WaitMs: wait.Milliseconds(),
+		Deck:   call.Deck,
+		Car:    lift.Car,
+	}))
+}
+
+// cancelCalls drops every floor still waiting to be visited. The current
+// destination is kept unless the trip is being abandoned or the lift is only
+// parking. It must be called with lift.mx held.
+func (lift *liftModel) cancelCalls(abandonTrip bool) {
+	lift.floorsToVisit.Clear()
+	if abandonTrip || lift.parkingAt != nil {
+		lift.destination = nil
+	}
+	lift.parkingAt = nil
+	for floor, handles := range lift.pendingCalls {
+		if lift.destination == nil || *lift.destination != floor {
+			for _, handle := range handles {
+				handle.cancel()
+			}
+			delete(lift.pendingCalls, floor)
+		}
+	}
+}
+
+func (lift *liftModel) signalWake() {
+	select {
+	case lift.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (lift *liftModel) nextDestination() (int, bool) {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	floors := lift.floorsToVisit.Values()
+	if len(floors) == 0 {
+		return 0, false
+	}
+	floor := lift.scheduler.next(floors, lift.Floor, lift.direction)
+	lift.floorsToVisit.Remove(floor)
+	lift.destination = &floor
+	lift.moving = false
+	return floor, true
+}
+
+func (lift *liftModel) setStatus(ctx context.Context, status LiftStatus) {
+	lift.Status = status
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_status_changed", LiftStatusChanged{Status: status}))
+}
+
+func (lift *liftModel) takeOutOfService(ctx context.Context, mode MaintenanceMode) bool {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status == StatusOutOfService {
+		return false
+	}
+	lift.cancelCalls(mode == AbandonTrip)
+	lift.setStatus(ctx, StatusOutOfService)
+	return true
+}
+
+func (lift *liftModel) returnToService(ctx context.Context) bool {
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status != StatusOutOfService {
+		return false
+	}
+	lift.setStatus(ctx, StatusInService)
+	lift.signalWake()
+	return true
+}
+
 func (lift *liftModel) handleCalls(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case floor := <-lift.callsChan:
-			if lift.currentFloor() == floor {
-				continue
-			}
-			if !lift.floorsToVisit.Has(floor) {
-				lift.floorsToVisit.Enqueue(floor)
-			}
+		case c := <-lift.callsChan:
+			lift.addFloorToVisit(ctx, c)
 		}
 	}
 }
 
+// handleFloorsToVisit runs the lift. Whenever it has been left idle it may
+// park, but only once until it is woken again. It returns once the lift has
+// been retired and drained.
 func (lift *liftModel) handleFloorsToVisit(ctx context.Context) {
-	// Send floors to visit to transit chan
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
-				nextFloor, err := lift.floorsToVisit.Dequeue()
-				if err != nil {
-					continue
+	armed := true
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lift.wake:
+			armed = true
+		case <-lift.idleTimeout(armed):
+			armed = false
+			if floor, ok := lift.park(ctx); ok {
+				for lift.transitTowards(ctx, floor) {
 				}
-				lift.transitChan <- nextFloor
 			}
 		}
-	}()
 
-	// Send floors to visit to transit chan
-	go func() {
 		for {
-			select {
-			case <-ctx.Done():
-				return
-			case nextFloor := <-lift.transitChan:
-				var delta int
-				if lift.currentFloor() > nextFloor {
-					delta = -1
-				} else {
-					delta = 1
-				}
-				for lift.currentFloor() != nextFloor {
-					lift.transitToFloor(ctx, delta)
-				}
-
-				lift.publish(ctx, createLiftEvent(lift.Id, "lift_arrived", LiftArrived{Floor: nextFloor}))
+			nextFloor, ok := lift.nextDestination()
+			if !ok {
+				break
+			}
+			for lift.transitTowards(ctx, nextFloor) {
 			}
 		}
-	}()
+		for lift.makeWay(ctx) {
+		}
+		if lift.drained() {
+			return
+		}
+	}
 }
 
 func (lift *liftModel) handleNotifications(ctx context.Context, publish publish) {
@@ -173,47 +511,122 @@ type publish func(ev any) error
 type LiftService struct {
 	liftOrder     []LiftId
 	lifts         map[LiftId]*liftModel
+	shafts        map[string]*shaft
 	mx            sync.Mutex
+	recallFloor   *int
 	lifecycleChan chan *liftModel
 	notifications chan LiftEvent
 	publish       publish
+	clock         clock.Clock
+	energy        EnergyModel
+	parking       ParkingConfig
+	access        map[int][]AccessRule // by floor
+	maxStops      int
+	lowestFloor   int
+	metrics       *liftMetrics
+	stats         *callStats
 }
 
-func NewLiftService(ctx context.Context, ps pubsub.PubSub) *LiftService {
+type Option func(svc *LiftService)
+
+// WithClock sets the clock lifts use to time their movement and calls.
+func WithClock(c clock.Clock) Option {
+	return func(svc *LiftService) {
+		svc.clock = c
+	}
+}
+
+func NewLiftService(ctx context.Context, ps pubsub.PubSub, opts ...Option) *LiftService {
 	publish := func(ev any) error {
 		return ps.Publish("lifts", ev)
 	}
 	svc := &LiftService{
 		lifts:         make(map[LiftId]*liftModel),
+		shafts:        make(map[string]*shaft),
 		mx:            sync.Mutex{},
 		lifecycleChan: make(chan *liftModel),
 		notifications: make(chan LiftEvent),
 		publish:       publish,
+		clock:         clock.Real,
+		energy:        DefaultEnergyModel,
+		stats:         newCallStats(defaultStatsWindow),
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
 	go svc.manageLiftLifecycle(ctx)
 	return svc
 }
 
+// publishEvent publishes a building-wide event that does not belong to any lift.
+func (svc *LiftService) publishEvent(ev LiftEvent) {
+	ev.OccurredAt = svc.clock.Now()
+	svc.publish(ev)
+}
+
 func (svc *LiftService) AddLift(ctx context.Context, cfg LiftConfig) (Lift, error) {
+	scheduler, err := parseScheduler(cfg.Scheduler)
+	if err != nil {
+		return Lift{}, err
+	}
+	if err := validateServedFloors(cfg); err != nil {
+		return Lift{}, err
+	}
+	decks := max(cfg.Decks, 1)
+	if decks > 2 {
+		return Lift{}, ErrInvalidDecks
+	}
+	if decks > 1 && cfg.Shaft != "" {
+		return Lift{}, ErrShaftDecks
+	}
 	svc.mx.Lock()
 	defer svc.mx.Unlock()
 	id := NewLiftId()
 	lift := Lift{
 		Id:           id,
 		Floor:        cfg.Floor,
+		Status:       StatusInService,
 		floorDelayMs: cfg.FloorDelayMs,
+		doorDwellMs:  cfg.DoorDwellMs,
+		ServedFloors: cfg.ServedFloors,
+		Decks:        decks,
+		Shaft:        cfg.Shaft,
+		scheduler:    scheduler,
 	}
-	liftModel := newLiftModel(lift)
+	liftModel := newLiftModel(lift, svc.clock, svc.energy, svc.metrics, svc.stats)
+	if cfg.Shaft != "" {
+		shaft, ok := svc.shafts[cfg.Shaft]
+		if !ok {
+			shaft = newShaft()
+		}
+		if liftModel.Car, err = shaft.join(liftModel); err != nil {
+			return Lift{}, err
+		}
+		svc.shafts[cfg.Shaft] = shaft
+		liftModel.shaft = shaft
+	}
+	liftModel.parking = svc.parking
+	liftModel.parkingFloor = svc.parkingFloor
+	liftModel.maxStops = svc.maxStops
+	liftModel.lowest = svc.lowestFloor
 	svc.lifts[id] = liftModel
 	svc.liftOrder = append(svc.liftOrder, id)
 	go func() {
 		svc.lifecycleChan <- liftModel
 	}()
 	liftModel.publish(ctx, createLiftEvent(liftModel.Id, "lift_added", LiftAdded{Floor: liftModel.currentFloor()}))
-	return lift, nil
+	if svc.recallFloor != nil {
+		liftModel.recall(ctx, *svc.recallFloor)
+	}
+	return liftModel.snapshot(), nil
 }
 
-var ErrLiftNotFound = errors.New("lift not found")
+var (
+	ErrLiftNotFound       = errors.New("lift not found")
+	ErrLiftOutOfService   = errors.New("lift is out of service")
+	ErrEmergencyRecall    = errors.New("emergency recall in progress")
+	ErrIndependentService = errors.New("lift is in independent service")
+)
 
 func (svc *LiftService) getLiftModel(id LiftId) (*liftModel, error) {
 	svc.mx.Lock()
@@ -231,10 +644,7 @@ func (svc *LiftService) GetLift(_ context.Context, id LiftId) (Lift, error) {
 		return Lift{}, err
 	}
 
-	return Lift{
-		Id:    id,
-		Floor: model.currentFloor(),
-	}, nil
+	return model.snapshot(), nil
 }
 
 func (svc *LiftService) GetLifts(_ context.Context) ([]Lift, error) {
@@ -247,19 +657,36 @@ func (svc *LiftService) GetLifts(_ context.Context) ([]Lift, error) {
 		if !ok {
 			continue
 		}
-		result[i] = Lift{Id: lift.Id, Floor: lift.currentFloor()}
+		result[i] = lift.snapshot()
 	}
 
 	return result, nil
 }
 
-func (svc *LiftService) CallLift(ctx context.Context, id LiftId, floor int) error {
+func (svc *LiftService) CallLift(ctx context.Context, id LiftId, floor int) (*CallHandle, error) {
+	requestedAt := svc.clock.Now()
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return model.call(ctx, liftCall{floor: floor, handle: newCallHandle(floor, requestedAt)})
+}
+
+// CarCall requests a floor from inside the lift. Unlike CallLift it is
+// honoured while the lift is in independent service. Restricted floors need
+// a credential attached to ctx with WithCredential.
+func (svc *LiftService) CarCall(ctx context.Context, id LiftId, floor int) (*CallHandle, error) {
+	requestedAt := svc.clock.Now()
 	model, err := svc.getLiftModel(id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := svc.authorise(ctx, id, floor); err != nil {
+		return nil, err
 	}
 
-	return model.call(ctx, floor)
+	return model.call(ctx, liftCall{floor: floor, carCall: true, handle: newCallHandle(floor, requestedAt)})
 }
 
 func (svc *LiftService) manageLiftLifecycle(ctx context.Context) {
@@ -274,9 +701,20 @@ func (svc *LiftService) manageLiftLifecycle(ctx context.Context) {
 }
 
 func (svc *LiftService) startLiftProcessing(ctx context.Context, lift *liftModel) {
-	go lift.handleCalls(ctx)
-	go lift.handleFloorsToVisit(ctx)
-	go lift.handleNotifications(ctx, svc.publish)
+	liftCtx, cancel := context.WithCancel(ctx)
+	go lift.handleCalls(liftCtx)
+	go func() {
+		lift.handleFloorsToVisit(liftCtx)
+		if ctx.Err() == nil {
+			// the lift was retired rather than the service stopped, and is
+			// forgotten before it stops publishing so that nothing finds it
+			// afterwards
+			svc.removeLift(lift)
+		}
+		close(lift.stopped)
+		cancel()
+	}()
+	go lift.handleNotifications(liftCtx, svc.publish)
 }
 
 type SubscriptionManager struct {
@@ -65,7 +65,7 @@ func Test_CallingLift(t *testing.T) {
 
 		id := NewLiftId()
 
-		err := svc.CallLift(context.TODO(), id, 5)
+		_, err := svc.CallLift(context.TODO(), id, 5)
 		if err == nil {
 			t.Error("expected an error, got nil")
 			return
@@ -87,7 +87,7 @@ func Test_CallingLift(t *testing.T) {
 			return
 		}
 
-		err = svc.CallLift(context.TODO(), lift.Id, 5)
+		_, err = svc.CallLift(context.TODO(), lift.Id, 5)
 		if err != nil {
 			t.Errorf("expected no error, got %e", err)
 		}
@@ -314,7 +314,7 @@ func Test_SubscriptionManager(t *testing.T) {
 			cancel()
 		}()
 		for i := 0; i < 50; i++ {
-			err := svc.CallLift(context.TODO(), lift.Id, i+1)
+			_, err := svc.CallLift(context.TODO(), lift.Id, i+1)
 			if err != nil {
 				t.Errorf("expected no error, got %e", err)
 				return
@@ -325,13 +325,15 @@ func Test_SubscriptionManager(t *testing.T) {
 		for i := 0; i < 50; i++ {
 			want = append(want, createLiftEvent(lift.Id, "lift_transited", LiftTransited{From: i, To: i + 1}))
 			want = append(want, createLiftEvent(lift.Id, "lift_arrived", LiftArrived{Floor: i + 1}))
+			want = append(want, createLiftEvent(lift.Id, "lift_energy", LiftEnergy{Floor: i + 1, TripWh: 20, TotalWh: float64(20 * (i + 1))}))
+			want = append(want, createLiftEvent(lift.Id, "lift_call_served", LiftCallServed{Floor: i + 1}))
 		}
 
 		wg := sync.WaitGroup{}
-		wg.Add(100)
+		wg.Add(200)
 		var got []LiftEvent
 		go func() {
-			for i := 0; i < 100; i++ {
+			for i := 0; i < 200; i++ {
 				ev := <-ch
 				got = append(got, ev)
 				wg.Done()
@@ -348,6 +350,15 @@ func Test_SubscriptionManager(t *testing.T) {
 				return
 			}
 
+			// call ids and wait times vary between runs
+			if served, ok := got[i].Data.(LiftCallServed); ok {
+				if served.Floor != want[i].Data.(LiftCallServed).Floor {
+					t.Errorf("expected %v, got %v", want[i].Data, served)
+					return
+				}
+				continue
+			}
+
 			if got[i].Data != want[i].Data {
 				t.Errorf("expected %T%v, got %T%v", got[i].Data, got[i].Data, want[i].Data, want[i].Data)
 				return
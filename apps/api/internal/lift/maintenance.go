@@ -0,0 +1,37 @@
+package lift
+
+import "context"
+
+type MaintenanceMode string
+
+const (
+	// FinishTrip lets the lift complete the trip it is on before standing idle.
+	FinishTrip MaintenanceMode = "finish_trip"
+	// AbandonTrip stops the lift at the next floor it reaches.
+	AbandonTrip MaintenanceMode = "abandon_trip"
+)
+
+// TakeOutOfService puts a lift into maintenance. Any floors still queued are
+// dropped and new calls are rejected with ErrLiftOutOfService until the lift
+// is returned to service.
+func (svc *LiftService) TakeOutOfService(ctx context.Context, id LiftId, mode MaintenanceMode) (Lift, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	model.takeOutOfService(ctx, mode)
+	return model.snapshot(), nil
+}
+
+func (svc *LiftService) ReturnToService(ctx context.Context, id LiftId) (Lift, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	if model.returnToService(ctx) {
+		if floor, active := svc.EmergencyRecall(); active {
+			model.recall(ctx, floor)
+		}
+	}
+	return model.snapshot(), nil
+}
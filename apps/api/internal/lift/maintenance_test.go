@@ -0,0 +1,138 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func nextEvent(t *testing.T, ch <-chan LiftEvent, eventType string) LiftEvent {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+			return LiftEvent{}
+		case ev := <-ch:
+			if ev.EventType == eventType {
+				return ev
+			}
+		}
+	}
+}
+
+func Test_Maintenance(t *testing.T) {
+	t.Run("a lift out of service rejects calls", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 2})
+		got, err := svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Status != StatusOutOfService {
+			t.Errorf("expected %s, got %s", StatusOutOfService, got.Status)
+		}
+
+		ev := nextEvent(t, ch, "lift_status_changed")
+		if ev.Data != (LiftStatusChanged{Status: StatusOutOfService}) {
+			t.Errorf("expected out of service event, got %v", ev.Data)
+		}
+
+		_, err = svc.CallLift(ctx, lift.Id, 5)
+		if !errors.Is(err, ErrLiftOutOfService) {
+			t.Errorf("expected out of service error, got %v", err)
+		}
+	})
+
+	t.Run("a lift returned to service accepts calls again", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 2})
+		svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+		got, err := svc.ReturnToService(ctx, lift.Id)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if got.Status != StatusInService {
+			t.Errorf("expected %s, got %s", StatusInService, got.Status)
+		}
+
+		if _, err := svc.CallLift(ctx, lift.Id, 3); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 3}) {
+			t.Errorf("expected arrival at 3, got %v", ev.Data)
+		}
+	})
+
+	t.Run("finishing the current trip drops queued floors", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 20})
+		svc.CallLift(ctx, lift.Id, 3)
+		svc.CallLift(ctx, lift.Id, 6)
+		nextEvent(t, ch, "lift_transited")
+		svc.TakeOutOfService(ctx, lift.Id, FinishTrip)
+
+		ev := nextEvent(t, ch, "lift_arrived")
+		if ev.Data != (LiftArrived{Floor: 3}) {
+			t.Errorf("expected arrival at 3, got %v", ev.Data)
+		}
+		nextEvent(t, ch, "lift_call_served")
+		select {
+		case ev := <-ch:
+			t.Errorf("expected no more events, got %v", ev)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("abandoning the current trip stops at the next floor", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 20})
+		svc.CallLift(ctx, lift.Id, 10)
+		nextEvent(t, ch, "lift_transited")
+		svc.TakeOutOfService(ctx, lift.Id, AbandonTrip)
+		nextEvent(t, ch, "lift_status_changed")
+
+		select {
+		case ev := <-ch:
+			t.Errorf("expected no more events, got %v", ev)
+		case <-time.After(100 * time.Millisecond):
+		}
+		got, _ := svc.GetLift(ctx, lift.Id)
+		if got.Floor >= 10 {
+			t.Errorf("expected lift to stop before floor 10, got %d", got.Floor)
+		}
+	})
+}
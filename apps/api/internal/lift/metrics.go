@@ -0,0 +1,86 @@
+package lift
+
+import "github.com/leow93/miffed-api/internal/metrics"
+
+type liftMetrics struct {
+	callsReceived   *metrics.Counter
+	callsServed     *metrics.Counter
+	waitTime        *metrics.Histogram
+	journeyTime     *metrics.Histogram
+	floorsTravelled *metrics.Counter
+}
+
+// WithMetrics records call, journey and travel metrics for every lift.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(svc *LiftService) {
+		svc.metrics = &liftMetrics{
+			callsReceived:   reg.NewCounter("miffed_lift_calls_received_total", "Calls accepted by a lift.", "lift_id"),
+			callsServed:     reg.NewCounter("miffed_lift_calls_served_total", "Calls served by a lift arriving at the called floor.", "lift_id"),
+			waitTime:        reg.NewHistogram("miffed_lift_wait_seconds", "Time from a call being made to the lift arriving.", metrics.DefBuckets, "lift_id"),
+			journeyTime:     reg.NewHistogram("miffed_lift_journey_seconds", "Time from a car call being made to the lift arriving at its floor.", metrics.DefBuckets, "lift_id"),
+			floorsTravelled: reg.NewCounter("miffed_lift_floors_travelled_total", "Floors travelled by a lift.", "lift_id"),
+		}
+		reg.NewGaugeFunc("miffed_lift_pending_stops", "Floors a lift has yet to visit, including its current destination.", svc.pendingStops, "lift_id")
+	}
+}
+
+func (svc *LiftService) pendingStops() []metrics.Sample {
+	svc.mx.Lock()
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	samples := make([]metrics.Sample, len(models))
+	for i, model := range models {
+		samples[i] = metrics.Sample{LabelValues: []string{model.Id.String()}, Value: float64(model.pendingStops())}
+	}
+	return samples
+}
+
+func (lift *liftModel) pendingStops() int {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	stops := lift.floorsToVisit.Length()
+	if lift.destination != nil {
+		stops++
+	}
+	return stops
+}
+
+func (m *liftMetrics) callReceived(id LiftId) {
+	if m == nil {
+		return
+	}
+	m.callsReceived.Inc(id.String())
+}
+
+// callServed records the wait for every call. A car call is made once the
+// passenger has boarded, so its wait is also their journey.
+func (m *liftMetrics) callServed(id LiftId, call Call) {
+	if m == nil {
+		return
+	}
+	m.callsServed.Inc(id.String())
+	m.waitTime.Observe(call.WaitTime().Seconds(), id.String())
+	if call.CarCall {
+		m.journeyTime.Observe(call.WaitTime().Seconds(), id.String())
+	}
+}
+
+// liftRemoved drops every series for a lift that has been removed.
+func (m *liftMetrics) liftRemoved(id LiftId) {
+	if m == nil {
+		return
+	}
+	m.callsReceived.Delete(id.String())
+	m.callsServed.Delete(id.String())
+	m.waitTime.Delete(id.String())
+	m.journeyTime.Delete(id.String())
+	m.floorsTravelled.Delete(id.String())
+}
+
+func (m *liftMetrics) floorTravelled(id LiftId) {
+	if m == nil {
+		return
+	}
+	m.floorsTravelled.Inc(id.String())
+}
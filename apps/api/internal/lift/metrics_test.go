@@ -0,0 +1,75 @@
+package lift
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/metrics"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Metrics(t *testing.T) {
+	t.Run("calls, car call journeys and floors travelled are recorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		reg := metrics.NewRegistry()
+		svc := NewLiftService(ctx, ps, WithMetrics(reg))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.CallLift(ctx, lift.Id, 3)
+		nextEvent(t, ch, "lift_arrived")
+		svc.CallLift(ctx, lift.Id, 3)
+		svc.CarCall(ctx, lift.Id, 5)
+
+		label := fmt.Sprintf(`{lift_id="%s"}`, lift.Id)
+		var output string
+		deadline := time.Now().Add(time.Second)
+		for !strings.Contains(output, "miffed_lift_calls_served_total"+label+" 3") && time.Now().Before(deadline) {
+			var buf bytes.Buffer
+			reg.Write(&buf)
+			output = buf.String()
+		}
+		for _, want := range []string{
+			"miffed_lift_calls_received_total" + label + " 3",
+			"miffed_lift_calls_served_total" + label + " 3",
+			"miffed_lift_wait_seconds_count" + label + " 3",
+			"miffed_lift_journey_seconds_count" + label + " 1",
+			"miffed_lift_floors_travelled_total" + label + " 5",
+			"miffed_lift_pending_stops" + label + " 0",
+		} {
+			if !strings.Contains(output, want+"\n") {
+				t.Errorf("expected output to contain %q, got\n%s", want, output)
+			}
+		}
+	})
+	t.Run("a removed lift's series are deleted", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		reg := metrics.NewRegistry()
+		svc := NewLiftService(ctx, ps, WithMetrics(reg))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.CarCall(ctx, lift.Id, 2)
+		nextEvent(t, ch, "lift_arrived")
+		svc.RetireLift(ctx, lift.Id)
+		nextEvent(t, ch, "lift_retired")
+
+		var buf bytes.Buffer
+		reg.Write(&buf)
+		if output := buf.String(); strings.Contains(output, lift.Id.String()) {
+			t.Errorf("expected no series for the removed lift, got\n%s", output)
+		}
+	})
+}
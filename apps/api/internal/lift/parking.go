@@ -0,0 +1,180 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ParkingPolicy decides where a lift goes once it has been idle for a while.
+type ParkingPolicy string
+
+const (
+	// ParkingNone leaves idle lifts wherever they stopped.
+	ParkingNone ParkingPolicy = "none"
+	// ParkingLobby returns idle lifts to the lobby.
+	ParkingLobby ParkingPolicy = "lobby"
+	// ParkingZones splits the building into one zone per lift and parks each
+	// lift in the middle of its own zone.
+	ParkingZones ParkingPolicy = "zones"
+	// ParkingDemand parks idle lifts at the floors with the most hall calls
+	// within the stats window, the first lift at the busiest floor, the
+	// second at the next busiest, and so on.
+	ParkingDemand ParkingPolicy = "demand"
+)
+
+// ParkingPolicies lists every supported parking policy.
+var ParkingPolicies = []ParkingPolicy{ParkingNone, ParkingLobby, ParkingZones, ParkingDemand}
+
+var ErrUnknownParkingPolicy = errors.New("unknown parking policy")
+
+// ParseParkingPolicy checks s is a supported policy. An empty string is
+// ParkingNone.
+func ParseParkingPolicy(s string) (ParkingPolicy, error) {
+	if s == "" {
+		return ParkingNone, nil
+	}
+	for _, known := range ParkingPolicies {
+		if ParkingPolicy(s) == known {
+			return known, nil
+		}
+	}
+	return "", ErrUnknownParkingPolicy
+}
+
+type ParkingConfig struct {
+	Policy ParkingPolicy
+	// IdleTimeout is how long a lift must have nothing to do before it parks.
+	IdleTimeout time.Duration
+	Lobby       int
+	// Floors is the number of floors in the building, numbered up from its
+	// lowest floor. It is only needed for ParkingZones.
+	Floors int
+}
+
+func (cfg ParkingConfig) enabled() bool {
+	return cfg.Policy != "" && cfg.Policy != ParkingNone && cfg.IdleTimeout > 0
+}
+
+// WithParking sets where lifts go when they are left idle.
+func WithParking(cfg ParkingConfig) Option {
+	return func(svc *LiftService) {
+		svc.parking = cfg
+	}
+}
+
+// parkingFloor is where the lift with id should park under the service's
+// policy. It returns false if the lift should stay where it is.
+func (svc *LiftService) parkingFloor(id LiftId) (int, bool) {
+	cfg := svc.parking
+	svc.mx.Lock()
+	index, count := -1, len(svc.liftOrder)
+	for i, liftId := range svc.liftOrder {
+		if liftId == id {
+			index = i
+		}
+	}
+	svc.mx.Unlock()
+	if index < 0 {
+		return 0, false
+	}
+
+	switch cfg.Policy {
+	case ParkingLobby:
+		return cfg.Lobby, true
+	case ParkingZones:
+		if cfg.Floors <= 0 {
+			return 0, false
+		}
+		lowest := index * cfg.Floors / count
+		highest := (index+1)*cfg.Floors/count - 1
+		if highest < lowest {
+			// more lifts than floors
+			return svc.lowestFloor + lowest, true
+		}
+		return svc.lowestFloor + (lowest+highest)/2, true
+	case ParkingDemand:
+		floors := svc.stats.busiestFloors(svc.clock.Now())
+		if len(floors) == 0 {
+			return 0, false
+		}
+		return floors[index%len(floors)], true
+	}
+	return 0, false
+}
+
+// busiestFloors ranks the floors hall calls were made from within the window,
+// busiest first.
+func (s *callStats) busiestFloors(now time.Time) []int {
+	if s == nil {
+		return nil
+	}
+	s.mx.Lock()
+	s.prune(now)
+	demand := make(map[int]int)
+	for _, call := range s.served {
+		if !call.CarCall {
+			demand[call.Floor]++
+		}
+	}
+	s.mx.Unlock()
+
+	floors := make([]int, 0, len(demand))
+	for floor := range demand {
+		floors = append(floors, floor)
+	}
+	sort.Slice(floors, func(i, j int) bool {
+		if demand[floors[i]] != demand[floors[j]] {
+			return demand[floors[i]] > demand[floors[j]]
+		}
+		return floors[i] < floors[j]
+	})
+	return floors
+}
+
+// idleTimeout fires once the lift has been idle long enough to park, if
+// parking is enabled and armed. Otherwise it never fires.
+func (lift *liftModel) idleTimeout(armed bool) <-chan time.Time {
+	if !armed || !lift.parking.enabled() {
+		return nil
+	}
+	return lift.clock.After(lift.parking.IdleTimeout)
+}
+
+// park sends the idle lift towards its parking floor, returning false if it
+// should stay where it is.
+func (lift *liftModel) park(ctx context.Context) (int, bool) {
+	floor, ok := lift.parkingFloor(lift.Id)
+	if !ok {
+		return 0, false
+	}
+	lift.mx.Lock()
+	defer lift.mx.Unlock()
+	if lift.Status != StatusInService || lift.Retiring || len(lift.faults) > 0 || lift.DoorsOpen ||
+		lift.destination != nil || lift.floorsToVisit.Length() > 0 || lift.Floor == floor || !lift.reaches(floor) {
+		return 0, false
+	}
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_parking", LiftParking{
+		From:   lift.Floor,
+		Floor:  floor,
+		Policy: lift.parking.Policy,
+	}))
+	lift.parkingAt = &floor
+	lift.destination = &floor
+	lift.moving = false
+	return floor, true
+}
+
+// cancelParking stops the lift heading for its parking floor so it can answer
+// a call. It must be called with lift.mx held.
+func (lift *liftModel) cancelParking(ctx context.Context) {
+	if lift.parkingAt == nil {
+		return
+	}
+	lift.publish(ctx, createLiftEvent(lift.Id, "lift_parking_cancelled", LiftParkingCancelled{
+		Floor: *lift.parkingAt,
+	}))
+	lift.parkingAt = nil
+	lift.destination = nil
+}
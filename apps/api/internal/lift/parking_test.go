@@ -0,0 +1,96 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Parking(t *testing.T) {
+	setup := func(t *testing.T, cfg ParkingConfig) (context.Context, *LiftService, <-chan LiftEvent) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps, WithParking(cfg))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		t.Cleanup(func() { subs.Unsubscribe(id) })
+		return ctx, svc, ch
+	}
+
+	t.Run("an idle lift returns to the lobby", func(t *testing.T) {
+		ctx, svc, ch := setup(t, ParkingConfig{Policy: ParkingLobby, IdleTimeout: 20 * time.Millisecond, Lobby: 1})
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 1})
+		handle, _ := svc.CarCall(ctx, l.Id, 3)
+		handle.Wait(ctx)
+
+		parking := nextEvent(t, ch, "lift_parking").Data.(LiftParking)
+		if parking != (LiftParking{From: 3, Floor: 1, Policy: ParkingLobby}) {
+			t.Errorf("expected to park at the lobby, got %+v", parking)
+		}
+		if arrived := nextEvent(t, ch, "lift_arrived").Data.(LiftArrived); arrived.Floor != 1 {
+			t.Errorf("expected to arrive at the lobby, got floor %d", arrived.Floor)
+		}
+	})
+
+	t.Run("lifts spread across zones", func(t *testing.T) {
+		ctx, svc, ch := setup(t, ParkingConfig{Policy: ParkingZones, IdleTimeout: 20 * time.Millisecond, Floors: 10})
+		a, _ := svc.AddLift(ctx, LiftConfig{Floor: 5})
+		b, _ := svc.AddLift(ctx, LiftConfig{Floor: 5})
+
+		parked := make(map[LiftId]int)
+		for range 2 {
+			ev := nextEvent(t, ch, "lift_parking")
+			parked[ev.LiftId] = ev.Data.(LiftParking).Floor
+		}
+		if parked[a.Id] != 2 || parked[b.Id] != 7 {
+			t.Errorf("expected lifts to park at floors 2 and 7, got %d and %d", parked[a.Id], parked[b.Id])
+		}
+	})
+
+	t.Run("an idle lift parks where demand is highest", func(t *testing.T) {
+		ctx, svc, ch := setup(t, ParkingConfig{Policy: ParkingDemand, IdleTimeout: 20 * time.Millisecond})
+		svc.AddLift(ctx, LiftConfig{Floor: 0})
+		for _, floor := range []int{4, 4, 2} {
+			handle, err := svc.Dispatch(ctx, floor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handle.Wait(ctx)
+		}
+
+		parking := nextEvent(t, ch, "lift_parking").Data.(LiftParking)
+		if parking != (LiftParking{From: 2, Floor: 4, Policy: ParkingDemand}) {
+			t.Errorf("expected to park at floor 4, got %+v", parking)
+		}
+	})
+
+	t.Run("a call cancels parking", func(t *testing.T) {
+		ctx, svc, ch := setup(t, ParkingConfig{Policy: ParkingLobby, IdleTimeout: 20 * time.Millisecond})
+		l, _ := svc.AddLift(ctx, LiftConfig{Floor: 5, FloorDelayMs: 50})
+		nextEvent(t, ch, "lift_parking")
+
+		handle, err := svc.CallLift(ctx, l.Id, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cancelled := nextEvent(t, ch, "lift_parking_cancelled").Data.(LiftParkingCancelled); cancelled.Floor != 0 {
+			t.Errorf("expected parking at the lobby to be cancelled, got %+v", cancelled)
+		}
+		if _, err := handle.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := svc.GetLift(ctx, l.Id); got.Floor != 6 {
+			t.Errorf("expected the lift to answer the call at floor 6, got floor %d", got.Floor)
+		}
+	})
+
+	t.Run("unknown policies are rejected", func(t *testing.T) {
+		if _, err := ParseParkingPolicy("anywhere"); !errors.Is(err, ErrUnknownParkingPolicy) {
+			t.Errorf("expected ErrUnknownParkingPolicy, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var ErrLiftRetiring = errors.New("lift is being retired")
+
+// LiftSettings are the settings of a lift that can be changed while it runs.
+type LiftSettings struct {
+	FloorDelayMs int
+	DoorDwellMs  int
+	Scheduler    Scheduler // defaults to SchedulerFIFO
+}
+
+type LiftUpdated struct {
+	FloorDelayMs int       `json:"floor_delay_ms"`
+	DoorDwellMs  int       `json:"door_dwell_ms"`
+	Scheduler    Scheduler `json:"scheduler"`
+}
+
+type LiftRetiring struct {
+	Floor int `json:"floor"`
+}
+
+type LiftRetired struct {
+	Floor int `json:"floor"`
+}
+
+// UpdateLift changes a lift's settings, taking effect from the next floor it
+// travels or stop it makes.
+func (svc *LiftService) UpdateLift(ctx context.Context, id LiftId, settings LiftSettings) (Lift, error) {
+	scheduler, err := parseScheduler(settings.Scheduler)
+	if err != nil {
+		return Lift{}, err
+	}
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	model.mx.Lock()
+	model.floorDelayMs = settings.FloorDelayMs
+	model.doorDwellMs = settings.DoorDwellMs
+	model.scheduler = scheduler
+	model.publish(ctx, createLiftEvent(id, "lift_updated", LiftUpdated{
+		FloorDelayMs: settings.FloorDelayMs,
+		DoorDwellMs:  settings.DoorDwellMs,
+		Scheduler:    scheduler,
+	}))
+	model.mx.Unlock()
+	return model.snapshot(), nil
+}
+
+// RetireLift stops a lift taking new calls, rejecting them with
+// ErrLiftRetiring, and removes it from the service once it has served the
+// calls it already has. A lift_retired event is published when it has gone.
+func (svc *LiftService) RetireLift(ctx context.Context, id LiftId) (Lift, error) {
+	model, err := svc.getLiftModel(id)
+	if err != nil {
+		return Lift{}, err
+	}
+	model.mx.Lock()
+	if !model.Retiring {
+		model.Retiring = true
+		model.cancelParking(ctx)
+		model.publish(ctx, createLiftEvent(id, "lift_retiring", LiftRetiring{Floor: model.Floor}))
+		model.signalWake()
+	}
+	model.mx.Unlock()
+	return model.snapshot(), nil
+}
+
+// drained reports whether a retiring lift has nothing left to do.
+func (lift *liftModel) drained() bool {
+	lift.mx.RLock()
+	defer lift.mx.RUnlock()
+	return lift.Retiring && len(lift.reserved) == 0 && lift.destination == nil &&
+		lift.floorsToVisit.Length() == 0 && len(lift.pendingCalls) == 0
+}
+
+// removeLift forgets a drained lift, which has stopped moving.
+func (svc *LiftService) removeLift(lift *liftModel) {
+	svc.mx.Lock()
+	delete(svc.lifts, lift.Id)
+	svc.liftOrder = slices.DeleteFunc(svc.liftOrder, func(id LiftId) bool { return id == lift.Id })
+	if lift.shaft != nil && lift.shaft.leave(lift) {
+		delete(svc.shafts, lift.Shaft)
+	}
+	svc.mx.Unlock()
+	svc.metrics.liftRemoved(lift.Id)
+	svc.publishEvent(createLiftEvent(lift.Id, "lift_retired", LiftRetired{Floor: lift.currentFloor()}))
+}
@@ -0,0 +1,113 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Reconfigure(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *LiftService, <-chan LiftEvent) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		t.Cleanup(func() { subs.Unsubscribe(id) })
+		return ctx, svc, ch
+	}
+
+	t.Run("a lift's settings can be updated", func(t *testing.T) {
+		ctx, svc, ch := setup(t)
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000})
+		if _, err := svc.UpdateLift(ctx, lift.Id, LiftSettings{FloorDelayMs: 5, DoorDwellMs: 5, Scheduler: SchedulerScan}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		ev := nextEvent(t, ch, "lift_updated")
+		if want := (LiftUpdated{FloorDelayMs: 5, DoorDwellMs: 5, Scheduler: SchedulerScan}); ev.Data != want {
+			t.Errorf("expected %+v, got %+v", want, ev.Data)
+		}
+
+		// at the old floor delay the lift would take 3s
+		svc.CallLift(ctx, lift.Id, 3)
+		nextEvent(t, ch, "lift_arrived")
+
+		if _, err := svc.UpdateLift(ctx, lift.Id, LiftSettings{Scheduler: "random"}); !errors.Is(err, ErrUnknownScheduler) {
+			t.Errorf("expected an unknown scheduler error, got %v", err)
+		}
+		if _, err := svc.UpdateLift(ctx, NewLiftId(), LiftSettings{}); !errors.Is(err, ErrLiftNotFound) {
+			t.Errorf("expected lift not found, got %v", err)
+		}
+	})
+
+	t.Run("a retired lift serves its calls before it is removed", func(t *testing.T) {
+		ctx, svc, ch := setup(t)
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 5})
+		handle, _ := svc.CallLift(ctx, lift.Id, 4)
+
+		got, err := svc.RetireLift(ctx, lift.Id)
+		if err != nil || !got.Retiring {
+			t.Fatalf("expected the lift to be retiring, got %+v, %v", got, err)
+		}
+		nextEvent(t, ch, "lift_retiring")
+		if _, err := svc.CallLift(ctx, lift.Id, 2); !errors.Is(err, ErrLiftRetiring) {
+			t.Errorf("expected a retiring error, got %v", err)
+		}
+		if _, err := svc.Dispatch(ctx, 2); !errors.Is(err, ErrNoLiftAvailable) {
+			t.Errorf("expected the retiring lift not to be dispatched, got %v", err)
+		}
+
+		if _, err := handle.Wait(ctx); err != nil {
+			t.Errorf("expected the pending call to be served, got %v", err)
+		}
+		ev := nextEvent(t, ch, "lift_retired")
+		if ev.LiftId != lift.Id || ev.Data != (LiftRetired{Floor: 4}) {
+			t.Errorf("expected the lift to retire at floor 4, got %+v", ev)
+		}
+		if _, err := svc.GetLift(ctx, lift.Id); !errors.Is(err, ErrLiftNotFound) {
+			t.Errorf("expected the lift to be removed, got %v", err)
+		}
+		if lifts, _ := svc.GetLifts(ctx); len(lifts) != 0 {
+			t.Errorf("expected no lifts, got %+v", lifts)
+		}
+	})
+
+	t.Run("a retired lift found before it was removed doesn't block", func(t *testing.T) {
+		ctx, svc, ch := setup(t)
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		model, _ := svc.getLiftModel(lift.Id)
+		svc.RetireLift(ctx, lift.Id)
+		nextEvent(t, ch, "lift_retired")
+
+		done := make(chan struct{})
+		go func() {
+			model.recall(context.Background(), 3)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected recalling the retired lift not to block")
+		}
+	})
+
+	t.Run("a retired car's place in its shaft can be taken", func(t *testing.T) {
+		ctx, svc, ch := setup(t)
+		lower, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, Shaft: "a"})
+		svc.AddLift(ctx, LiftConfig{Floor: 5, Shaft: "a"})
+
+		svc.RetireLift(ctx, lower.Id)
+		nextEvent(t, ch, "lift_retired")
+		if _, err := svc.AddLift(ctx, LiftConfig{Floor: 6, Shaft: "a"}); !errors.Is(err, ErrShaftOrder) {
+			t.Errorf("expected a car above the upper car to be refused, got %v", err)
+		}
+		added, err := svc.AddLift(ctx, LiftConfig{Floor: 1, Shaft: "a"})
+		if err != nil || added.Car != CarLower {
+			t.Errorf("expected a new lower car, got %+v, %v", added, err)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package lift
+
+import "errors"
+
+// Scheduler decides the order in which a lift visits its pending floors.
+type Scheduler string
+
+const (
+	// SchedulerFIFO visits floors in the order they were called.
+	SchedulerFIFO Scheduler = "fifo"
+	// SchedulerNearest always heads for the closest pending floor, and stops
+	// at any pending floor it passes.
+	SchedulerNearest Scheduler = "nearest"
+	// SchedulerScan keeps travelling in one direction while there are floors
+	// to visit ahead, stopping at each on the way, before reversing.
+	SchedulerScan Scheduler = "scan"
+)
+
+// Schedulers lists every supported scheduler.
+var Schedulers = []Scheduler{SchedulerFIFO, SchedulerNearest, SchedulerScan}
+
+var ErrUnknownScheduler = errors.New("unknown scheduler")
+
+func parseScheduler(s Scheduler) (Scheduler, error) {
+	if s == "" {
+		return SchedulerFIFO, nil
+	}
+	for _, known := range Schedulers {
+		if s == known {
+			return s, nil
+		}
+	}
+	return "", ErrUnknownScheduler
+}
+
+// next picks which of floors to visit from the lift's current floor and
+// direction of travel (-1, 0 or 1). floors must not be empty.
+func (s Scheduler) next(floors []int, current, direction int) int {
+	switch s {
+	case SchedulerNearest:
+		return nearestFloor(floors, current)
+	case SchedulerScan:
+		if direction != 0 {
+			if floor, ok := nearestAhead(floors, current, direction); ok {
+				return floor
+			}
+		}
+		return nearestFloor(floors, current)
+	default:
+		return floors[0]
+	}
+}
+
+// stopsOnTheWay reports whether the scheduler lets the lift stop at pending
+// floors it passes on the way to its destination.
+func (s Scheduler) stopsOnTheWay() bool {
+	return s == SchedulerNearest || s == SchedulerScan
+}
+
+func nearestFloor(floors []int, current int) int {
+	best := floors[0]
+	for _, floor := range floors[1:] {
+		if abs(floor-current) < abs(best-current) {
+			best = floor
+		}
+	}
+	return best
+}
+
+func nearestAhead(floors []int, current, direction int) (int, bool) {
+	var best int
+	found := false
+	for _, floor := range floors {
+		ahead := (floor - current) * direction
+		if ahead <= 0 {
+			continue
+		}
+		if !found || ahead < (best-current)*direction {
+			best = floor
+			found = true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,88 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Scheduler(t *testing.T) {
+	t.Run("picks the next floor to visit", func(t *testing.T) {
+		floors := []int{8, 3, 6}
+		cases := []struct {
+			scheduler Scheduler
+			direction int
+			want      int
+		}{
+			{SchedulerFIFO, 0, 8},
+			{SchedulerNearest, 0, 6},
+			{SchedulerScan, 1, 6},
+			{SchedulerScan, -1, 3},
+			{SchedulerScan, 0, 6},
+		}
+		for _, c := range cases {
+			if got := c.scheduler.next(floors, 5, c.direction); got != c.want {
+				t.Errorf("%s heading %d: expected %d, got %d", c.scheduler, c.direction, c.want, got)
+			}
+		}
+	})
+
+	t.Run("scan reverses once nothing is left ahead", func(t *testing.T) {
+		if got := SchedulerScan.next([]int{1, 2}, 5, 1); got != 2 {
+			t.Errorf("expected 2, got %d", got)
+		}
+	})
+
+	t.Run("unknown schedulers are rejected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		if _, err := svc.AddLift(ctx, LiftConfig{Scheduler: "lifo"}); !errors.Is(err, ErrUnknownScheduler) {
+			t.Errorf("expected unknown scheduler error, got %v", err)
+		}
+	})
+
+	arrivals := func(t *testing.T, scheduler Scheduler) []int {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		v := clock.NewVirtual(time.Now())
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps, WithClock(v))
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 1000, Scheduler: scheduler})
+		nextEvent(t, ch, "lift_added")
+
+		for _, floor := range []int{5, 2} {
+			if _, err := svc.CallLift(ctx, lift.Id, floor); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		}
+		go v.Drive(ctx, time.Millisecond)
+
+		var floors []int
+		for len(floors) < 2 {
+			ev := nextEvent(t, ch, "lift_arrived")
+			floors = append(floors, ev.Data.(LiftArrived).Floor)
+		}
+		return floors
+	}
+
+	t.Run("fifo visits floors in the order they were called", func(t *testing.T) {
+		if got := arrivals(t, SchedulerFIFO); got[0] != 5 || got[1] != 2 {
+			t.Errorf("expected arrivals at [5 2], got %v", got)
+		}
+	})
+
+	t.Run("scan stops at floors on the way", func(t *testing.T) {
+		if got := arrivals(t, SchedulerScan); got[0] != 2 || got[1] != 5 {
+			t.Errorf("expected arrivals at [2 5], got %v", got)
+		}
+	})
+}
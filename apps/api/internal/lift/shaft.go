@@ -0,0 +1,221 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Car is which of the two cars sharing a shaft a lift is.
+type Car string
+
+const (
+	CarLower Car = "lower"
+	CarUpper Car = "upper"
+)
+
+var (
+	ErrShaftFull = errors.New("shaft already has two cars")
+	// ErrShaftOrder is returned when a lift added to a shaft would start at
+	// or below the car already in it. The first car added is the lower one,
+	// unless it is added after the lower car was retired.
+	ErrShaftOrder = errors.New("the upper car must start above the lower car")
+	ErrShaftDecks = errors.New("double-deck lifts cannot share a shaft")
+)
+
+// shaft is shared by two independent cars, TWIN style, which can never pass
+// or reach each other: the lower car always stays below the upper car. When
+// a car is blocked by the other it asks the other to clear the way to its
+// destination, and the other holds beyond that floor until the blocked car
+// arrives. Only one car can be asking at a time, so the cars never both wait
+// on each other.
+type shaft struct {
+	cars    [2]*liftModel // lower, upper
+	floors  [2]int
+	clear   [2]*int // floor each car has asked the other to clear
+	changed chan struct{}
+	mx      sync.Mutex
+}
+
+func newShaft() *shaft {
+	return &shaft{changed: make(chan struct{})}
+}
+
+// join adds lift to the shaft, returning which car it is. It must be called
+// before the lift starts running.
+func (s *shaft) join(lift *liftModel) (Car, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	switch {
+	case s.cars[0] != nil && s.cars[1] != nil:
+		return "", ErrShaftFull
+	case s.cars[1] != nil:
+		if lift.Floor >= s.floors[1] {
+			return "", ErrShaftOrder
+		}
+	case s.cars[0] == nil:
+	case lift.Floor <= s.floors[0]:
+		return "", ErrShaftOrder
+	default:
+		s.cars[1], s.floors[1] = lift, lift.Floor
+		return CarUpper, nil
+	}
+	s.cars[0], s.floors[0] = lift, lift.Floor
+	return CarLower, nil
+}
+
+// leave removes a retired lift from the shaft, releasing any way either car
+// asked the other to clear. It reports whether the shaft is now empty.
+func (s *shaft) leave(lift *liftModel) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	i := s.index(lift)
+	s.cars[i] = nil
+	s.clear = [2]*int{}
+	s.changedLocked()
+	return s.cars[1-i] == nil
+}
+
+// index must be called with s.mx held.
+func (s *shaft) index(lift *liftModel) int {
+	if s.cars[1] == lift {
+		return 1
+	}
+	return 0
+}
+
+// The following methods are safe to call on a nil shaft, for lifts with a
+// shaft to themselves, and may be called with lift.mx held.
+
+// reaches reports whether lift can ever stop at floor. The upper car cannot
+// reach the lowest floor either car serves, nor the lower car the highest.
+func (s *shaft) reaches(lift *liftModel, floor int) bool {
+	if s == nil {
+		return true
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	i := s.index(lift)
+	other := s.cars[1-i]
+	if other == nil {
+		return true
+	}
+	if i == 1 {
+		return floor > min(lift.lowestFloor(), other.lowestFloor())
+	}
+	highest, bounded := highestFloor(lift.ServedFloors)
+	otherHighest, otherBounded := highestFloor(other.ServedFloors)
+	return !bounded || !otherBounded || floor < max(highest, otherHighest)
+}
+
+// target is the floor lift should head for on its way to floor, which is
+// beyond any floor the other car has asked it to clear. The returned channel
+// is closed when either car next moves or changes what it has asked.
+func (s *shaft) target(lift *liftModel, floor int) (int, <-chan struct{}) {
+	if s == nil {
+		return floor, nil
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	i := s.index(lift)
+	clear := s.clear[1-i]
+	switch {
+	case clear == nil:
+	case i == 0 && floor >= *clear:
+		floor = *clear - 1
+	case i == 1 && floor <= *clear:
+		floor = *clear + 1
+	}
+	return floor, s.changed
+}
+
+// move moves lift to floor if the other car is not in the way. Otherwise,
+// unless the other car is already waiting for lift to clear its way, it asks
+// the other car to clear the way to destination, and returns a channel closed
+// when either car next moves or changes what it has asked.
+func (s *shaft) move(lift *liftModel, floor, destination int) (bool, <-chan struct{}) {
+	if s == nil {
+		return true, nil
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	i := s.index(lift)
+	other := s.cars[1-i]
+	if other == nil || (i == 0 && floor < s.floors[1]) || (i == 1 && floor > s.floors[0]) {
+		s.floors[i] = floor
+		s.changedLocked()
+		return true, nil
+	}
+	if s.clear[1-i] == nil && (s.clear[i] == nil || *s.clear[i] != destination) {
+		s.clear[i] = &destination
+		s.changedLocked()
+		other.signalWake()
+	}
+	return false, s.changed
+}
+
+// release withdraws lift's request for the other car to clear its way.
+func (s *shaft) release(lift *liftModel) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	i := s.index(lift)
+	if s.clear[i] != nil {
+		s.clear[i] = nil
+		s.changedLocked()
+	}
+}
+
+// changedLocked must be called with s.mx held.
+func (s *shaft) changedLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// highestFloor is the highest of ranges, if the lift doesn't serve every floor.
+func highestFloor(ranges []FloorRange) (int, bool) {
+	if len(ranges) == 0 {
+		return 0, false
+	}
+	highest := ranges[0].To
+	for _, r := range ranges[1:] {
+		highest = max(highest, r.To)
+	}
+	return highest, true
+}
+
+// makeWay moves an idle lift one floor further out of the way of the other
+// car in its shaft, returning false once it is clear.
+func (lift *liftModel) makeWay(ctx context.Context) bool {
+	lift.mx.Lock()
+	if lift.shaft == nil || lift.destination != nil || lift.immobilised() || lift.Status == StatusOutOfService {
+		lift.mx.Unlock()
+		return false
+	}
+	target, _ := lift.shaft.target(lift, lift.Floor)
+	if target == lift.Floor {
+		lift.mx.Unlock()
+		return false
+	}
+	to := lift.Floor + 1
+	if target < lift.Floor {
+		to = lift.Floor - 1
+	}
+	if ok, _ := lift.shaft.move(lift, to, target); !ok {
+		lift.mx.Unlock()
+		return false
+	}
+	lift.moveTo(ctx, to)
+	return true
+}
+
+func waitForShaft(ctx context.Context, changed <-chan struct{}) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-changed:
+		return true
+	}
+}
@@ -0,0 +1,87 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Shaft(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *LiftService, Lift, Lift) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lower, err := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 10, Shaft: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		upper, err := svc.AddLift(ctx, LiftConfig{Floor: 5, FloorDelayMs: 10, Shaft: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx, svc, lower, upper
+	}
+
+	t.Run("a shaft holds two cars, the upper above the lower", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		lower, _ := svc.AddLift(ctx, LiftConfig{Floor: 2, Shaft: "a"})
+		if _, err := svc.AddLift(ctx, LiftConfig{Floor: 2, Shaft: "a"}); !errors.Is(err, ErrShaftOrder) {
+			t.Errorf("expected ErrShaftOrder, got %v", err)
+		}
+		upper, _ := svc.AddLift(ctx, LiftConfig{Floor: 3, Shaft: "a"})
+		if _, err := svc.AddLift(ctx, LiftConfig{Floor: 4, Shaft: "a"}); !errors.Is(err, ErrShaftFull) {
+			t.Errorf("expected ErrShaftFull, got %v", err)
+		}
+		if lower.Car != CarLower || upper.Car != CarUpper {
+			t.Errorf("expected lower and upper cars, got %s and %s", lower.Car, upper.Car)
+		}
+	})
+
+	t.Run("the upper car cannot reach the lowest floor", func(t *testing.T) {
+		ctx, svc, _, upper := setup(t)
+		if _, err := svc.CallLift(ctx, upper.Id, 0); !errors.Is(err, ErrFloorNotServed) {
+			t.Errorf("expected ErrFloorNotServed, got %v", err)
+		}
+	})
+
+	t.Run("an idle car moves out of the way", func(t *testing.T) {
+		ctx, svc, lower, upper := setup(t)
+		handle, _ := svc.CarCall(ctx, lower.Id, 8)
+		if _, err := handle.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := svc.GetLift(ctx, upper.Id); got.Floor != 9 {
+			t.Errorf("expected the upper car to have moved up to floor 9, got %d", got.Floor)
+		}
+
+		// and back down again
+		handle, _ = svc.CarCall(ctx, upper.Id, 3)
+		if _, err := handle.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := svc.GetLift(ctx, lower.Id); got.Floor != 2 {
+			t.Errorf("expected the lower car to have moved down to floor 2, got %d", got.Floor)
+		}
+	})
+
+	t.Run("cars heading towards each other take turns", func(t *testing.T) {
+		ctx, svc, lower, upper := setup(t)
+		up, _ := svc.CarCall(ctx, lower.Id, 7)
+		down, _ := svc.CarCall(ctx, upper.Id, 1)
+		if _, err := up.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := down.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+		l, _ := svc.GetLift(ctx, lower.Id)
+		u, _ := svc.GetLift(ctx, upper.Id)
+		if l.Floor >= u.Floor {
+			t.Errorf("expected the upper car above the lower car, got %d and %d", u.Floor, l.Floor)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package lift
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultStatsWindow = 15 * time.Minute
+
+// WithStatsWindow sets how far back Stats looks when summarising wait times.
+func WithStatsWindow(window time.Duration) Option {
+	return func(svc *LiftService) {
+		svc.stats = newCallStats(window)
+	}
+}
+
+type WaitStats struct {
+	Count   int
+	Average time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	Max     time.Duration
+}
+
+type Stats struct {
+	Window  time.Duration
+	Overall WaitStats
+	ByLift  map[LiftId]WaitStats
+	ByFloor map[int]WaitStats
+	// EnergyWh is each lift's energy use since it was added, regardless of
+	// the window.
+	EnergyWh      map[LiftId]float64
+	TotalEnergyWh float64
+}
+
+// Stats summarises the wait time of calls served within the stats window,
+// and the energy each lift has used.
+func (svc *LiftService) Stats(_ context.Context) (Stats, error) {
+	stats := svc.stats.summarise(svc.clock.Now())
+	svc.mx.Lock()
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	stats.EnergyWh = make(map[LiftId]float64, len(models))
+	for _, model := range models {
+		l := model.snapshot()
+		stats.EnergyWh[l.Id] = l.EnergyWh
+		stats.TotalEnergyWh += l.EnergyWh
+	}
+	return stats, nil
+}
+
+type callStats struct {
+	window time.Duration
+	served []Call // ordered by arrival
+	mx     sync.Mutex
+}
+
+func newCallStats(window time.Duration) *callStats {
+	return &callStats{window: window}
+}
+
+func (s *callStats) record(call Call) {
+	if s == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.served = append(s.served, call)
+	s.prune(call.ArrivedAt)
+}
+
+// prune must be called with s.mx held.
+func (s *callStats) prune(now time.Time) {
+	cutoff := now.Add(-s.window)
+	i := sort.Search(len(s.served), func(i int) bool {
+		return s.served[i].ArrivedAt.After(cutoff)
+	})
+	s.served = s.served[i:]
+}
+
+func (s *callStats) summarise(now time.Time) Stats {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.prune(now)
+
+	var overall []time.Duration
+	byLift := make(map[LiftId][]time.Duration)
+	byFloor := make(map[int][]time.Duration)
+	for _, call := range s.served {
+		wait := call.WaitTime()
+		overall = append(overall, wait)
+		byLift[call.LiftId] = append(byLift[call.LiftId], wait)
+		byFloor[call.Floor] = append(byFloor[call.Floor], wait)
+	}
+
+	stats := Stats{
+		Window:  s.window,
+		Overall: summariseWaits(overall),
+		ByLift:  make(map[LiftId]WaitStats, len(byLift)),
+		ByFloor: make(map[int]WaitStats, len(byFloor)),
+	}
+	for id, waits := range byLift {
+		stats.ByLift[id] = summariseWaits(waits)
+	}
+	for floor, waits := range byFloor {
+		stats.ByFloor[floor] = summariseWaits(waits)
+	}
+	return stats
+}
+
+func summariseWaits(waits []time.Duration) WaitStats {
+	if len(waits) == 0 {
+		return WaitStats{}
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	var total time.Duration
+	for _, wait := range waits {
+		total += wait
+	}
+	return WaitStats{
+		Count:   len(waits),
+		Average: total / time.Duration(len(waits)),
+		P50:     percentile(waits, 50),
+		P95:     percentile(waits, 95),
+		Max:     waits[len(waits)-1],
+	}
+}
+
+// percentile uses the nearest-rank method on sorted waits.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
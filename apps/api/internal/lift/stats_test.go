@@ -0,0 +1,123 @@
+package lift
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_CallTracking(t *testing.T) {
+	t.Run("serving a call publishes its id and wait time", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, FloorDelayMs: 5})
+		handle, err := svc.CallLift(ctx, lift.Id, 2)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		call := handle.Call()
+		if call.LiftId != lift.Id || call.Floor != 2 || call.RequestedAt.IsZero() || call.AssignedAt.IsZero() {
+			t.Errorf("expected a recorded call, got %+v", call)
+		}
+
+		ev := nextEvent(t, ch, "lift_call_served")
+		served, ok := ev.Data.(LiftCallServed)
+		if !ok {
+			t.Fatalf("expected LiftCallServed, got %T", ev.Data)
+		}
+		if served.CallId != call.Id || served.Floor != 2 {
+			t.Errorf("expected call %s at floor 2, got %+v", call.Id, served)
+		}
+		if served.WaitMs < 10 {
+			t.Errorf("expected a wait of at least 10ms, got %d", served.WaitMs)
+		}
+	})
+
+	t.Run("calls at the lift's floor are served immediately", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 4})
+		handle, _ := svc.CallLift(ctx, lift.Id, 4)
+		call := handle.Call()
+
+		ev := nextEvent(t, ch, "lift_call_served")
+		if served := ev.Data.(LiftCallServed); served.CallId != call.Id {
+			t.Errorf("expected call %s, got %+v", call.Id, served)
+		}
+	})
+}
+
+func Test_Stats(t *testing.T) {
+	t.Run("summarises wait times per lift and per floor", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := NewLiftService(ctx, ps)
+		subs := NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		lift, _ := svc.AddLift(ctx, LiftConfig{Floor: 0})
+		svc.CallLift(ctx, lift.Id, 1)
+		nextEvent(t, ch, "lift_call_served")
+		svc.CallLift(ctx, lift.Id, 2)
+		nextEvent(t, ch, "lift_call_served")
+
+		stats, err := svc.Stats(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if stats.Overall.Count != 2 {
+			t.Errorf("expected 2 calls, got %d", stats.Overall.Count)
+		}
+		if stats.ByLift[lift.Id].Count != 2 {
+			t.Errorf("expected 2 calls for lift, got %d", stats.ByLift[lift.Id].Count)
+		}
+		if stats.ByFloor[1].Count != 1 || stats.ByFloor[2].Count != 1 {
+			t.Errorf("expected one call per floor, got %+v", stats.ByFloor)
+		}
+	})
+
+	t.Run("computes percentiles", func(t *testing.T) {
+		s := newCallStats(time.Hour)
+		now := time.Now()
+		for i := 1; i <= 20; i++ {
+			s.record(Call{Floor: 1, RequestedAt: now, ArrivedAt: now.Add(time.Duration(i) * time.Second)})
+		}
+
+		got := s.summarise(now).Overall
+		want := WaitStats{Count: 20, Average: 10500 * time.Millisecond, P50: 10 * time.Second, P95: 19 * time.Second, Max: 20 * time.Second}
+		if got != want {
+			t.Errorf("expected %+v, got %+v", want, got)
+		}
+	})
+
+	t.Run("only includes calls served within the window", func(t *testing.T) {
+		s := newCallStats(time.Minute)
+		now := time.Now()
+		s.record(Call{Floor: 1, RequestedAt: now.Add(-3 * time.Minute), ArrivedAt: now.Add(-2 * time.Minute)})
+		s.record(Call{Floor: 2, RequestedAt: now.Add(-time.Minute), ArrivedAt: now.Add(-30 * time.Second)})
+
+		got := s.summarise(now)
+		if got.Overall.Count != 1 {
+			t.Errorf("expected 1 call, got %d", got.Overall.Count)
+		}
+		if _, ok := got.ByFloor[1]; ok {
+			t.Errorf("expected floor 1 to have aged out, got %+v", got.ByFloor)
+		}
+	})
+}
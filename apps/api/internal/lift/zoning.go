@@ -0,0 +1,230 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// FloorRange is an inclusive range of floors. A single floor has From == To.
+type FloorRange struct {
+	From int
+	To   int
+}
+
+func (r FloorRange) contains(floor int) bool {
+	return floor >= r.From && floor <= r.To
+}
+
+var (
+	ErrInvalidFloorRange = errors.New("invalid floor range")
+	ErrFloorNotServed    = errors.New("floor not served by lift")
+	ErrNoRoute           = errors.New("no lifts connect those floors")
+)
+
+// FloorNotServedError is returned when a lift is called to a floor outside
+// its served floors. It matches ErrFloorNotServed.
+type FloorNotServedError struct {
+	LiftId LiftId
+	Floor  int
+}
+
+func (e *FloorNotServedError) Error() string {
+	return fmt.Sprintf("floor %d not served by lift %s", e.Floor, e.LiftId)
+}
+
+func (e *FloorNotServedError) Is(target error) bool {
+	return target == ErrFloorNotServed
+}
+
+func validateServedFloors(cfg LiftConfig) error {
+	for _, r := range cfg.ServedFloors {
+		if r.From > r.To {
+			return fmt.Errorf("%w: %d-%d", ErrInvalidFloorRange, r.From, r.To)
+		}
+	}
+	if !serves(cfg.ServedFloors, cfg.Floor) {
+		return fmt.Errorf("%w: the lift starts at floor %d", ErrFloorNotServed, cfg.Floor)
+	}
+	return nil
+}
+
+// Serves reports whether the lift stops at floor. A lift with no served
+// floors serves every floor.
+func (l Lift) Serves(floor int) bool {
+	return serves(l.ServedFloors, floor)
+}
+
+// serves lets a liftModel check its floors without copying the Lift it embeds.
+// ServedFloors never changes once a lift is added, so needs no lock.
+func serves(ranges []FloorRange, floor int) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, r := range ranges {
+		if r.contains(floor) {
+			return true
+		}
+	}
+	return false
+}
+
+// reaches reports whether the lift can stop at floor, given its served floors
+// and any other car in its shaft.
+func (lift *liftModel) reaches(floor int) bool {
+	return serves(lift.ServedFloors, floor) && lift.shaft.reaches(lift, floor)
+}
+
+func (l Lift) servedFloorsBetween(lowest, highest int) []int {
+	var floors []int
+	for floor := lowest; floor <= highest; floor++ {
+		if l.Serves(floor) {
+			floors = append(floors, floor)
+		}
+	}
+	return floors
+}
+
+// Leg is one ride of a journey through the building.
+type Leg struct {
+	LiftId LiftId
+	From   int
+	To     int
+}
+
+// PlanJourney finds the fewest lift rides from origin to destination among
+// the lifts in service. When no lift serves both floors the journey changes
+// lifts at floors both serve, such as a sky lobby. Each leg names the nearest
+// suitable lift right now; later legs may be answered by another lift serving
+// the same floors by the time the passenger gets there.
+func (svc *LiftService) PlanJourney(origin, destination int) ([]Leg, error) {
+	svc.mx.Lock()
+	models := svc.liftModels()
+	svc.mx.Unlock()
+
+	var lifts []Lift
+	for _, model := range models {
+		if model.available() {
+			lifts = append(lifts, model.snapshot())
+		}
+	}
+	if len(lifts) == 0 {
+		return nil, ErrNoLiftAvailable
+	}
+
+	floors := planTransfers(lifts, origin, destination)
+	if floors == nil {
+		return nil, fmt.Errorf("%w: %d to %d", ErrNoRoute, origin, destination)
+	}
+	legs := make([]Leg, 0, len(floors)-1)
+	for i := 1; i < len(floors); i++ {
+		from, to := floors[i-1], floors[i]
+		var best *Lift
+		for j, l := range lifts {
+			if !l.Serves(from) || !l.Serves(to) {
+				continue
+			}
+			if best == nil || abs(l.Floor-from) < abs(best.Floor-from) {
+				best = &lifts[j]
+			}
+		}
+		legs = append(legs, Leg{LiftId: best.Id, From: from, To: to})
+	}
+	return legs, nil
+}
+
+// planTransfers searches breadth first from the lifts serving origin to one
+// serving destination, and returns the floors the passenger stops at,
+// origin and destination included. Where two lifts share several floors the
+// passenger changes at the one closest to where they are. It returns nil if
+// no lifts connect the floors.
+func planTransfers(lifts []Lift, origin, destination int) []int {
+	lowest, highest := min(origin, destination), max(origin, destination)
+	for _, l := range lifts {
+		for _, r := range l.ServedFloors {
+			lowest, highest = min(lowest, r.From), max(highest, r.To)
+		}
+	}
+
+	type step struct {
+		lift  int
+		floor int // where the passenger boards this lift
+		prev  *step
+	}
+	var queue []*step
+	visited := make([]bool, len(lifts))
+	for i, l := range lifts {
+		if l.Serves(origin) {
+			queue = append(queue, &step{lift: i, floor: origin})
+			visited[i] = true
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		current := lifts[s.lift]
+		if current.Serves(destination) {
+			floors := []int{destination}
+			for ; s != nil; s = s.prev {
+				floors = append(floors, s.floor)
+			}
+			slices.Reverse(floors)
+			return floors
+		}
+		for i, next := range lifts {
+			if visited[i] {
+				continue
+			}
+			transfer, ok := nearestShared(current, next, s.floor, lowest, highest)
+			if !ok {
+				continue
+			}
+			visited[i] = true
+			queue = append(queue, &step{lift: i, floor: transfer, prev: s})
+		}
+	}
+	return nil
+}
+
+func nearestShared(a, b Lift, from, lowest, highest int) (int, bool) {
+	var shared []int
+	for _, floor := range a.servedFloorsBetween(lowest, highest) {
+		if b.Serves(floor) && floor != from {
+			shared = append(shared, floor)
+		}
+	}
+	if len(shared) == 0 {
+		return 0, false
+	}
+	return nearestFloor(shared, from), true
+}
+
+// DispatchTo assigns a hall call at origin, for a passenger travelling to
+// destination, to the nearest lift in service that serves both. If they
+// must change lifts on the way, the call is for the first leg and the
+// passenger calls again from the transfer floor. The planned legs are
+// returned with the call. A restricted destination needs a credential
+// attached to ctx, as for CarCall.
+func (svc *LiftService) DispatchTo(ctx context.Context, origin, destination int) (*CallHandle, []Leg, error) {
+	requestedAt := svc.clock.Now()
+	if _, active := svc.EmergencyRecall(); active {
+		return nil, nil, ErrEmergencyRecall
+	}
+	if err := svc.authorise(ctx, LiftId{}, destination); err != nil {
+		return nil, nil, err
+	}
+	legs, err := svc.PlanJourney(origin, destination)
+	if err != nil {
+		return nil, nil, err
+	}
+	model, err := svc.getLiftModel(legs[0].LiftId)
+	if err != nil {
+		return nil, nil, err
+	}
+	handle, err := model.call(ctx, liftCall{floor: origin, handle: newCallHandle(origin, requestedAt)})
+	if err != nil {
+		return nil, nil, err
+	}
+	return handle, legs, nil
+}
@@ -0,0 +1,105 @@
+package lift
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func Test_Zoning(t *testing.T) {
+	setup := func(t *testing.T) (context.Context, *LiftService) {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return ctx, NewLiftService(ctx, pubsub.NewMemoryPubSub())
+	}
+
+	t.Run("lifts must start on a served floor within valid ranges", func(t *testing.T) {
+		ctx, svc := setup(t)
+		if _, err := svc.AddLift(ctx, LiftConfig{ServedFloors: []FloorRange{{From: 10, To: 0}}}); !errors.Is(err, ErrInvalidFloorRange) {
+			t.Errorf("expected ErrInvalidFloorRange, got %v", err)
+		}
+		if _, err := svc.AddLift(ctx, LiftConfig{Floor: 5, ServedFloors: []FloorRange{{From: 10, To: 20}}}); !errors.Is(err, ErrFloorNotServed) {
+			t.Errorf("expected ErrFloorNotServed, got %v", err)
+		}
+	})
+
+	t.Run("calls to unserved floors are rejected", func(t *testing.T) {
+		ctx, svc := setup(t)
+		express, _ := svc.AddLift(ctx, LiftConfig{ServedFloors: []FloorRange{{From: 0, To: 0}, {From: 10, To: 20}}})
+
+		_, err := svc.CallLift(ctx, express.Id, 5)
+		var notServed *FloorNotServedError
+		if !errors.As(err, &notServed) || notServed.Floor != 5 || notServed.LiftId != express.Id {
+			t.Errorf("expected FloorNotServedError for floor 5, got %v", err)
+		}
+		if _, err := svc.CarCall(ctx, express.Id, 21); !errors.Is(err, ErrFloorNotServed) {
+			t.Errorf("expected ErrFloorNotServed, got %v", err)
+		}
+		if _, err := svc.CallLift(ctx, express.Id, 15); err != nil {
+			t.Errorf("expected a served floor to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("dispatch skips lifts that don't serve the floor", func(t *testing.T) {
+		ctx, svc := setup(t)
+		low, _ := svc.AddLift(ctx, LiftConfig{Floor: 0, ServedFloors: []FloorRange{{From: 0, To: 10}}})
+		svc.AddLift(ctx, LiftConfig{Floor: 11, ServedFloors: []FloorRange{{From: 0, To: 0}, {From: 11, To: 20}}})
+
+		handle, err := svc.Dispatch(ctx, 9)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if call, _ := handle.Wait(ctx); call.LiftId != low.Id {
+			t.Errorf("expected the low rise lift to answer, got %s", call.LiftId)
+		}
+	})
+
+	t.Run("journeys change lifts at a shared floor", func(t *testing.T) {
+		ctx, svc := setup(t)
+		low, _ := svc.AddLift(ctx, LiftConfig{ServedFloors: []FloorRange{{From: 0, To: 10}}})
+		shuttle, _ := svc.AddLift(ctx, LiftConfig{ServedFloors: []FloorRange{{From: 0, To: 0}, {From: 30, To: 30}}})
+		high, _ := svc.AddLift(ctx, LiftConfig{Floor: 30, ServedFloors: []FloorRange{{From: 30, To: 40}}})
+
+		tests := []struct {
+			origin, destination int
+			want                []Leg
+		}{
+			{3, 7, []Leg{{LiftId: low.Id, From: 3, To: 7}}},
+			{0, 35, []Leg{{LiftId: shuttle.Id, From: 0, To: 30}, {LiftId: high.Id, From: 30, To: 35}}},
+			{5, 35, []Leg{{LiftId: low.Id, From: 5, To: 0}, {LiftId: shuttle.Id, From: 0, To: 30}, {LiftId: high.Id, From: 30, To: 35}}},
+		}
+		for _, tt := range tests {
+			legs, err := svc.PlanJourney(tt.origin, tt.destination)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(legs, tt.want) {
+				t.Errorf("%d to %d: expected %+v, got %+v", tt.origin, tt.destination, tt.want, legs)
+			}
+		}
+
+		if _, err := svc.PlanJourney(5, 20); !errors.Is(err, ErrNoRoute) {
+			t.Errorf("expected ErrNoRoute, got %v", err)
+		}
+	})
+
+	t.Run("dispatching a journey calls the lift for the first leg", func(t *testing.T) {
+		ctx, svc := setup(t)
+		svc.AddLift(ctx, LiftConfig{Floor: 10, ServedFloors: []FloorRange{{From: 10, To: 20}}})
+		low, _ := svc.AddLift(ctx, LiftConfig{Floor: 8, ServedFloors: []FloorRange{{From: 0, To: 10}}})
+
+		handle, legs, err := svc.DispatchTo(ctx, 2, 15)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(legs) != 2 || legs[0].To != 10 {
+			t.Fatalf("expected to change lifts at floor 10, got %+v", legs)
+		}
+		if call, _ := handle.Wait(ctx); call.LiftId != low.Id || call.Floor != 2 {
+			t.Errorf("expected the low rise lift to answer at floor 2, got %+v", call)
+		}
+	})
+}
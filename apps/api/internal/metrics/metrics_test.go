@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatalf("got error %v, want nil", err)
+	}
+	return buf.String()
+}
+
+func assertContains(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("expected output to contain %q, got\n%s", line, output)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("counters", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("calls_total", "Calls made.", "lift_id")
+		c.Inc("a")
+		c.Inc("a")
+		c.Add(3, "b")
+		c.Add(-1, "b")
+
+		assertContains(t, render(t, r),
+			"# HELP calls_total Calls made.",
+			"# TYPE calls_total counter",
+			`calls_total{lift_id="a"} 2`,
+			`calls_total{lift_id="b"} 3`,
+		)
+	})
+
+	t.Run("gauges", func(t *testing.T) {
+		r := NewRegistry()
+		g := r.NewGauge("temperature", "Temperature.")
+		g.Set(10)
+		g.Add(-2.5)
+
+		assertContains(t, render(t, r), "# TYPE temperature gauge", "temperature 7.5")
+	})
+
+	t.Run("gauge funcs are read when written", func(t *testing.T) {
+		r := NewRegistry()
+		value := 1.0
+		r.NewGaugeFunc("queue_length", "Queue length.", func() []Sample {
+			return []Sample{{LabelValues: []string{"q"}, Value: value}}
+		}, "queue")
+		value = 4
+
+		assertContains(t, render(t, r), `queue_length{queue="q"} 4`)
+	})
+
+	t.Run("histograms", func(t *testing.T) {
+		r := NewRegistry()
+		h := r.NewHistogram("wait_seconds", "Wait time.", []float64{1, 5})
+		h.Observe(0.5)
+		h.Observe(2)
+		h.Observe(10)
+
+		assertContains(t, render(t, r),
+			"# TYPE wait_seconds histogram",
+			`wait_seconds_bucket{le="1"} 1`,
+			`wait_seconds_bucket{le="5"} 2`,
+			`wait_seconds_bucket{le="+Inf"} 3`,
+			"wait_seconds_sum 12.5",
+			"wait_seconds_count 3",
+		)
+	})
+
+	t.Run("series can be deleted", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("calls_total", "Calls made.", "lift_id")
+		h := r.NewHistogram("wait_seconds", "Wait time.", []float64{1}, "lift_id")
+		c.Inc("a")
+		c.Inc("b")
+		h.Observe(0.5, "a")
+		c.Delete("a")
+		h.Delete("a")
+
+		output := render(t, r)
+		assertContains(t, output, `calls_total{lift_id="b"} 1`)
+		if strings.Contains(output, `lift_id="a"`) {
+			t.Errorf("expected the deleted series to be gone, got\n%s", output)
+		}
+	})
+
+	t.Run("label values are escaped", func(t *testing.T) {
+		r := NewRegistry()
+		c := r.NewCounter("things_total", "Things.", "name")
+		c.Inc("a \"quoted\"\nvalue")
+		c.Inc(`C:\lifts`)
+		c.Inc("café\t✓")
+
+		assertContains(t, render(t, r),
+			`things_total{name="a \"quoted\"\nvalue"} 1`,
+			`things_total{name="C:\\lifts"} 1`,
+			"things_total{name=\"café\t✓\"} 1",
+		)
+	})
+}
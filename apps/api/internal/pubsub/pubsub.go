@@ -2,8 +2,10 @@ package pubsub
 
 import (
 	"context"
-	"github.com/google/uuid"
 	"sync"
+
+	"github.com/google/uuid"
+	"github.com/leow93/miffed-api/internal/metrics"
 )
 
 type Message interface{}
@@ -24,6 +26,27 @@ type subscriber struct {
 type MemoryPubSub struct {
 	subscribers map[Topic]map[uuid.UUID]subscriber
 	mutex       sync.Mutex
+	dropped     *metrics.Counter
+}
+
+type Option func(ps *MemoryPubSub)
+
+// WithMetrics reports the number of subscribers and dropped messages per topic.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(ps *MemoryPubSub) {
+		ps.dropped = reg.NewCounter("miffed_pubsub_messages_dropped_total", "Messages not delivered because the subscriber went away.", "topic")
+		reg.NewGaugeFunc("miffed_pubsub_subscribers", "Active subscribers, including websocket clients.", ps.subscriberCounts, "topic")
+	}
+}
+
+func (ps *MemoryPubSub) subscriberCounts() []metrics.Sample {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	samples := make([]metrics.Sample, 0, len(ps.subscribers))
+	for topic, subs := range ps.subscribers {
+		samples = append(samples, metrics.Sample{LabelValues: []string{string(topic)}, Value: float64(len(subs))})
+	}
+	return samples
 }
 
 func (ps *MemoryPubSub) addSubscriber(topic Topic, id uuid.UUID) <-chan Message {
@@ -51,13 +74,13 @@ func (ps *MemoryPubSub) Publish(topic Topic, message Message) error {
 
 	for _, s := range ps.subscribers[topic] {
 		go func(s subscriber) {
+			wg.Done()
 			select {
 			case <-s.ctx.Done():
-				wg.Done()
-				return
-			default:
-				wg.Done()
-				s.ch <- message
+				if ps.dropped != nil {
+					ps.dropped.Inc(string(topic))
+				}
+			case s.ch <- message:
 			}
 		}(s)
 	}
@@ -82,8 +105,12 @@ func (ps *MemoryPubSub) Unsubscribe(id uuid.UUID) {
 	}
 }
 
-func NewMemoryPubSub() *MemoryPubSub {
-	return &MemoryPubSub{
+func NewMemoryPubSub(opts ...Option) *MemoryPubSub {
+	ps := &MemoryPubSub{
 		subscribers: make(map[Topic]map[uuid.UUID]subscriber),
 	}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	return ps
 }
@@ -1,8 +1,12 @@
 package pubsub
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/leow93/miffed-api/internal/metrics"
 )
 
 func TestMemoryPubSub(t *testing.T) {
@@ -121,4 +125,27 @@ func TestMemoryPubSub(t *testing.T) {
 		}()
 		<-time.After(200 * time.Millisecond)
 	})
+
+	t.Run("reporting metrics", func(t *testing.T) {
+		reg := metrics.NewRegistry()
+		pubsub := NewMemoryPubSub(WithMetrics(reg))
+		id, _, _ := pubsub.Subscribe("foo")
+		pubsub.Subscribe("foo")
+		pubsub.Publish("foo", "never read")
+		<-time.After(10 * time.Millisecond)
+		pubsub.Unsubscribe(id)
+		<-time.After(10 * time.Millisecond)
+
+		var buf bytes.Buffer
+		reg.Write(&buf)
+		output := buf.String()
+		for _, want := range []string{
+			`miffed_pubsub_subscribers{topic="foo"} 1`,
+			`miffed_pubsub_messages_dropped_total{topic="foo"} 1`,
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got\n%s", want, output)
+			}
+		}
+	})
 }
@@ -51,3 +51,31 @@ func (q *Queue) Has(x int) bool {
 	}
 	return false
 }
+
+func (q *Queue) Clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.queue = nil
+}
+
+// Values returns a copy of the queue's contents in order.
+func (q *Queue) Values() []int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	values := make([]int, len(q.queue))
+	copy(values, q.queue)
+	return values
+}
+
+// Remove deletes the first occurrence of x, reporting whether it was found.
+func (q *Queue) Remove(x int) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, y := range q.queue {
+		if y == x {
+			q.queue = append(q.queue[:i:i], q.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -66,6 +66,36 @@ func TestQueue(t *testing.T) {
 		}
 	})
 
+	t.Run("queue can be cleared", func(t *testing.T) {
+		q := NewQueue()
+		q.Enqueue(1)
+		q.Enqueue(2)
+		q.Clear()
+		if q.Length() != 0 {
+			t.Fatalf("expected queue length to be 0, got %d", q.Length())
+		}
+		if _, err := q.Dequeue(); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	})
+
+	t.Run("values can be removed from anywhere in the queue", func(t *testing.T) {
+		q := NewQueue()
+		q.Enqueue(1)
+		q.Enqueue(2)
+		q.Enqueue(3)
+		if !q.Remove(2) {
+			t.Fatalf("expected 2 to be removed")
+		}
+		if q.Remove(4) {
+			t.Fatalf("expected 4 not to be found")
+		}
+		values := q.Values()
+		if len(values) != 2 || values[0] != 1 || values[1] != 3 {
+			t.Fatalf("expected [1 3], got %v", values)
+		}
+	})
+
 	t.Run("concurrent operations", func(t *testing.T) {
 		q := NewQueue()
 		wg := sync.WaitGroup{}
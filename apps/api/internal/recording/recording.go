@@ -0,0 +1,130 @@
+// Package recording captures the commands sent to a LiftService and the
+// events it publishes, so that a session can be replayed into a fresh
+// service later.
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+type CommandType string
+
+const (
+	CommandAddLift  CommandType = "add_lift"
+	CommandCallLift CommandType = "call_lift"
+	// CommandDispatch is a hall call the service chose a lift for, optionally
+	// with a destination.
+	CommandDispatch                CommandType = "dispatch"
+	CommandCarCall                 CommandType = "car_call"
+	CommandStartMaintenance        CommandType = "start_maintenance"
+	CommandEndMaintenance          CommandType = "end_maintenance"
+	CommandStartIndependentService CommandType = "start_independent_service"
+	CommandEndIndependentService   CommandType = "end_independent_service"
+	CommandStartEmergencyRecall    CommandType = "start_emergency_recall"
+	CommandClearEmergencyRecall    CommandType = "clear_emergency_recall"
+	CommandInjectFault             CommandType = "inject_fault"
+	CommandClearFault              CommandType = "clear_fault"
+	// CommandUpdateLift changes a lift's floor delay, door dwell and
+	// scheduler.
+	CommandUpdateLift CommandType = "update_lift"
+	CommandRetireLift CommandType = "retire_lift"
+)
+
+// Command is an inbound request to the LiftService. LiftId is the id the lift
+// had when the command was recorded. Passengers' credentials aren't
+// recorded, so replays run without access rules.
+type Command struct {
+	Type         CommandType       `json:"type"`
+	LiftId       lift.LiftId       `json:"lift_id"`
+	Floor        int               `json:"floor"`
+	FloorDelayMs int               `json:"floor_delay_ms,omitempty"`
+	DoorDwellMs  int               `json:"door_dwell_ms,omitempty"`
+	Scheduler    lift.Scheduler    `json:"scheduler,omitempty"`
+	ServedFloors []lift.FloorRange `json:"served_floors,omitempty"`
+	Decks        int               `json:"decks,omitempty"`
+	Shaft        string            `json:"shaft,omitempty"`
+	// Destination is set on dispatched calls that had one.
+	Destination *int                 `json:"destination,omitempty"`
+	Mode        lift.MaintenanceMode `json:"mode,omitempty"`
+	// Fault, SlowdownFactor and DropRate describe a fault injected or
+	// cleared.
+	Fault          lift.FaultType `json:"fault,omitempty"`
+	SlowdownFactor float64        `json:"slowdown_factor,omitempty"`
+	DropRate       float64        `json:"drop_rate,omitempty"`
+}
+
+// Entry is a single line of a recording: either a command or an event.
+type Entry struct {
+	At      time.Time       `json:"at"`
+	Command *Command        `json:"command,omitempty"`
+	Event   *lift.LiftEvent `json:"event,omitempty"`
+}
+
+// Recorder writes entries as JSON lines. A nil Recorder records nothing, so
+// callers don't need to check whether recording is enabled.
+type Recorder struct {
+	enc *json.Encoder
+	err error
+	mx  sync.Mutex
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+func (r *Recorder) RecordCommand(cmd Command) {
+	r.write(Entry{At: time.Now(), Command: &cmd})
+}
+
+func (r *Recorder) RecordEvent(ev lift.LiftEvent) {
+	r.write(Entry{At: ev.OccurredAt, Event: &ev})
+}
+
+// RecordEvents subscribes to subs and records every event published from
+// then until ctx is done.
+func (r *Recorder) RecordEvents(ctx context.Context, subs *lift.SubscriptionManager) error {
+	id, ch, err := subs.Subscribe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer subs.Unsubscribe(id)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-ch:
+				r.RecordEvent(ev)
+			}
+		}
+	}()
+	return nil
+}
+
+// Err returns the first error encountered writing the recording.
+func (r *Recorder) Err() error {
+	if r == nil {
+		return nil
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.err
+}
+
+func (r *Recorder) write(entry Entry) {
+	if r == nil {
+		return
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(entry)
+}
@@ -0,0 +1,252 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+// lockedBuffer lets a test read what a recorder has written so far.
+type lockedBuffer struct {
+	buf bytes.Buffer
+	mx  sync.Mutex
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+func TestRecorder(t *testing.T) {
+	t.Run("commands and events are read back in order", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		rec := NewRecorder(&buf)
+		id := lift.NewLiftId()
+		rec.RecordCommand(Command{Type: CommandAddLift, LiftId: id, Floor: 2, FloorDelayMs: 100})
+		rec.RecordEvent(lift.LiftEvent{EventType: "lift_added", LiftId: id, OccurredAt: time.Now()})
+		rec.RecordCommand(Command{Type: CommandCallLift, LiftId: id, Floor: 5})
+		if err := rec.Err(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		entries, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d", len(entries))
+		}
+		if cmd := entries[0].Command; cmd == nil || !reflect.DeepEqual(*cmd, Command{Type: CommandAddLift, LiftId: id, Floor: 2, FloorDelayMs: 100}) {
+			t.Errorf("expected the add lift command, got %+v", entries[0])
+		}
+		if ev := entries[1].Event; ev == nil || ev.EventType != "lift_added" || ev.LiftId != id {
+			t.Errorf("expected the lift_added event, got %+v", entries[1])
+		}
+		if cmd := entries[2].Command; cmd == nil || cmd.Type != CommandCallLift || cmd.Floor != 5 {
+			t.Errorf("expected the call lift command, got %+v", entries[2])
+		}
+	})
+
+	t.Run("events published by the service are recorded", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := lift.NewLiftService(ctx, ps)
+		subs := lift.NewSubscriptionManager(ctx, ps)
+		buf := &lockedBuffer{}
+		rec := NewRecorder(buf)
+		if err := rec.RecordEvents(ctx, subs); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		svc.AddLift(ctx, lift.LiftConfig{Floor: 1})
+		time.Sleep(10 * time.Millisecond)
+
+		entries, err := Read(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(entries) != 1 || entries[0].Event == nil || entries[0].Event.EventType != "lift_added" {
+			t.Errorf("expected a lift_added event, got %+v", entries)
+		}
+	})
+
+	t.Run("a nil recorder records nothing", func(t *testing.T) {
+		var rec *Recorder
+		rec.RecordCommand(Command{Type: CommandAddLift})
+		if err := rec.Err(); err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	})
+}
+
+func TestReplay(t *testing.T) {
+	recordedId := lift.NewLiftId()
+	start := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("commands are replayed against new lifts", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		c := clock.NewScaled(10)
+		svc := lift.NewLiftService(ctx, ps, lift.WithClock(c))
+		subs := lift.NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		entries := []Entry{
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: recordedId, Floor: 0, FloorDelayMs: 100}},
+			{At: start.Add(50 * time.Millisecond), Event: &lift.LiftEvent{EventType: "lift_added", LiftId: recordedId}},
+			{At: start.Add(time.Second), Command: &Command{Type: CommandCallLift, LiftId: recordedId, Floor: 2}},
+		}
+		started := time.Now()
+		outcomes, err := Replay(ctx, entries, svc, c)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(outcomes) != 2 || outcomes[0].Diverged() || outcomes[1].Diverged() {
+			t.Errorf("expected 2 commands to be replayed, got %+v", outcomes)
+		}
+		if elapsed := time.Since(started); elapsed < 90*time.Millisecond || elapsed > 500*time.Millisecond {
+			t.Errorf("expected the replay to take about a tenth of the recording, took %s", elapsed)
+		}
+
+		want := []string{"lift_added", "lift_transited", "lift_transited", "lift_arrived"}
+		for _, eventType := range want {
+			select {
+			case ev := <-ch:
+				if ev.EventType != eventType {
+					t.Fatalf("expected %s, got %s", eventType, ev.EventType)
+				}
+				if ev.LiftId == recordedId {
+					t.Fatalf("expected the replayed lift to have a new id")
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for %s", eventType)
+			}
+		}
+	})
+
+	t.Run("dispatched calls and lift commands are replayed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := lift.NewLiftService(ctx, ps)
+		subs := lift.NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		otherId := lift.NewLiftId()
+		entries := []Entry{
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: recordedId, Floor: 0, FloorDelayMs: 5}},
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: otherId, Floor: 4, FloorDelayMs: 5}},
+			{At: start, Command: &Command{Type: CommandStartMaintenance, LiftId: otherId, Mode: lift.FinishTrip}},
+			{At: start, Command: &Command{Type: CommandDispatch, Floor: 3}},
+		}
+		outcomes, err := Replay(ctx, entries, svc, clock.Real)
+		if err != nil || len(outcomes) != 4 {
+			t.Fatalf("expected 4 commands to be replayed, got %+v, %v", outcomes, err)
+		}
+
+		lifts, _ := svc.GetLifts(ctx)
+		if len(lifts) != 2 || lifts[1].Status != lift.StatusOutOfService {
+			t.Fatalf("expected the second lift to be out of service, got %+v", lifts)
+		}
+		deadline := time.After(time.Second)
+		for {
+			select {
+			case ev := <-ch:
+				if ev.EventType != "lift_arrived" {
+					continue
+				}
+				if ev.LiftId != lifts[0].Id || ev.Data != (lift.LiftArrived{Floor: 3}) {
+					t.Errorf("expected the lift in service to be dispatched to 3, got %+v", ev)
+				}
+				return
+			case <-deadline:
+				t.Fatal("timed out waiting for the dispatched lift to arrive")
+			}
+		}
+	})
+
+	t.Run("lifts are updated and retired", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ps := pubsub.NewMemoryPubSub()
+		svc := lift.NewLiftService(ctx, ps)
+		subs := lift.NewSubscriptionManager(ctx, ps)
+		id, ch, _ := subs.Subscribe()
+		defer subs.Unsubscribe(id)
+
+		otherId := lift.NewLiftId()
+		entries := []Entry{
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: recordedId, Floor: 0, FloorDelayMs: 1000}},
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: otherId, Floor: 4}},
+			{At: start, Command: &Command{Type: CommandUpdateLift, LiftId: recordedId, FloorDelayMs: 5, Scheduler: lift.SchedulerScan}},
+			{At: start, Command: &Command{Type: CommandRetireLift, LiftId: otherId}},
+		}
+		outcomes, err := Replay(ctx, entries, svc, clock.Real)
+		if err != nil || len(outcomes) != 4 {
+			t.Fatalf("expected 4 commands to be replayed, got %+v, %v", outcomes, err)
+		}
+
+		updated, retired := false, false
+		deadline := time.After(time.Second)
+		for !updated || !retired {
+			select {
+			case ev := <-ch:
+				switch ev.EventType {
+				case "lift_updated":
+					updated = ev.Data == (lift.LiftUpdated{FloorDelayMs: 5, Scheduler: lift.SchedulerScan})
+				case "lift_retired":
+					retired = true
+				}
+			case <-deadline:
+				t.Fatalf("timed out waiting for one lift to be updated and the other retired")
+			}
+		}
+		if lifts, _ := svc.GetLifts(ctx); len(lifts) != 1 {
+			t.Errorf("expected one lift left, got %+v", lifts)
+		}
+	})
+
+	t.Run("commands that fail diverge without ending the replay", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		entries := []Entry{
+			{At: start, Command: &Command{Type: CommandCallLift, LiftId: recordedId, Floor: 2}},
+			{At: start, Command: &Command{Type: CommandStartEmergencyRecall, Floor: 0}},
+			{At: start, Command: &Command{Type: CommandStartEmergencyRecall, Floor: 0}},
+			{At: start, Command: &Command{Type: CommandAddLift, LiftId: recordedId, Floor: 0}},
+		}
+		outcomes, err := Replay(ctx, entries, svc, clock.Real)
+		if err != nil || len(outcomes) != 4 {
+			t.Fatalf("expected every command to be replayed, got %+v, %v", outcomes, err)
+		}
+		if !errors.Is(outcomes[0].Err, lift.ErrLiftNotFound) {
+			t.Errorf("expected calling a lift that was never added to fail, got %v", outcomes[0].Err)
+		}
+		if outcomes[1].Diverged() || !errors.Is(outcomes[2].Err, lift.ErrEmergencyRecall) {
+			t.Errorf("expected only the second recall to fail, got %v and %v", outcomes[1].Err, outcomes[2].Err)
+		}
+		if outcomes[3].Diverged() {
+			t.Errorf("expected the lift to be added, got %v", outcomes[3].Err)
+		}
+	})
+}
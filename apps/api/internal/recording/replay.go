@@ -0,0 +1,145 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// Read parses a recording.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	dec := json.NewDecoder(r)
+	for {
+		var entry Entry
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// Outcome is how replaying a recorded command went. Commands are only
+// recorded once they have succeeded, so one with an Err has diverged from the
+// recorded session.
+type Outcome struct {
+	// At is when the command was recorded.
+	At      time.Time
+	Command Command
+	Err     error
+}
+
+func (o Outcome) Diverged() bool {
+	return o.Err != nil
+}
+
+// Replay issues the recorded commands to svc, keeping the gaps between them
+// as measured by c, and returns the outcome of each. A command that fails is
+// skipped rather than ending the replay. Lifts added during the replay get
+// new ids, which are substituted for the recorded ones in later commands.
+// Recorded events are skipped; svc publishes its own as the commands play
+// out. The error is only ever ctx's, if it is done before every command has
+// been replayed.
+func Replay(ctx context.Context, entries []Entry, svc *lift.LiftService, c clock.Clock) ([]Outcome, error) {
+	var recordedStart time.Time
+	for _, entry := range entries {
+		if entry.Command != nil {
+			recordedStart = entry.At
+			break
+		}
+	}
+	start := c.Now()
+	ids := make(map[lift.LiftId]lift.LiftId)
+	var outcomes []Outcome
+
+	for _, entry := range entries {
+		cmd := entry.Command
+		if cmd == nil {
+			continue
+		}
+		if wait := entry.At.Sub(recordedStart) - c.Now().Sub(start); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return outcomes, ctx.Err()
+			case <-c.After(wait):
+			}
+		}
+
+		outcomes = append(outcomes, Outcome{At: entry.At, Command: *cmd, Err: replay(ctx, svc, ids, cmd)})
+	}
+	return outcomes, nil
+}
+
+func replay(ctx context.Context, svc *lift.LiftService, ids map[lift.LiftId]lift.LiftId, cmd *Command) error {
+	if cmd.Type == CommandAddLift {
+		l, err := svc.AddLift(ctx, lift.LiftConfig{
+			Floor:        cmd.Floor,
+			FloorDelayMs: cmd.FloorDelayMs,
+			DoorDwellMs:  cmd.DoorDwellMs,
+			Scheduler:    cmd.Scheduler,
+			ServedFloors: cmd.ServedFloors,
+			Decks:        cmd.Decks,
+			Shaft:        cmd.Shaft,
+		})
+		if err != nil {
+			return err
+		}
+		ids[cmd.LiftId] = l.Id
+		return nil
+	}
+
+	var err error
+	switch cmd.Type {
+	case CommandDispatch:
+		if cmd.Destination != nil {
+			_, _, err = svc.DispatchTo(ctx, cmd.Floor, *cmd.Destination)
+		} else {
+			_, err = svc.Dispatch(ctx, cmd.Floor)
+		}
+		return err
+	case CommandStartEmergencyRecall:
+		return svc.StartEmergencyRecall(ctx, cmd.Floor)
+	case CommandClearEmergencyRecall:
+		return svc.ClearEmergencyRecall(ctx)
+	}
+
+	id, ok := ids[cmd.LiftId]
+	if !ok {
+		return fmt.Errorf("%w: %s was never added", lift.ErrLiftNotFound, cmd.LiftId)
+	}
+	switch cmd.Type {
+	case CommandCallLift:
+		_, err = svc.CallLift(ctx, id, cmd.Floor)
+	case CommandCarCall:
+		_, err = svc.CarCall(ctx, id, cmd.Floor)
+	case CommandStartMaintenance:
+		_, err = svc.TakeOutOfService(ctx, id, cmd.Mode)
+	case CommandEndMaintenance:
+		_, err = svc.ReturnToService(ctx, id)
+	case CommandStartIndependentService:
+		_, err = svc.StartIndependentService(ctx, id)
+	case CommandEndIndependentService:
+		_, err = svc.EndIndependentService(ctx, id)
+	case CommandInjectFault:
+		_, err = svc.InjectFault(ctx, id, lift.Fault{Type: cmd.Fault, SlowdownFactor: cmd.SlowdownFactor, DropRate: cmd.DropRate})
+	case CommandClearFault:
+		_, err = svc.ClearFault(ctx, id, cmd.Fault)
+	case CommandUpdateLift:
+		_, err = svc.UpdateLift(ctx, id, lift.LiftSettings{FloorDelayMs: cmd.FloorDelayMs, DoorDwellMs: cmd.DoorDwellMs, Scheduler: cmd.Scheduler})
+	case CommandRetireLift:
+		_, err = svc.RetireLift(ctx, id)
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Type)
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+// settle is how long the virtual clock must go unused, in real time, before
+// the runner assumes the lifts are waiting on it and moves time forward.
+const settle = time.Millisecond
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Report lists the differences between what a scenario expected and what
+// happened. A scenario with no diffs passed.
+type Report struct {
+	Scenario string
+	Diffs    []string
+}
+
+func (r Report) Passed() bool {
+	return len(r.Diffs) == 0
+}
+
+func (r Report) String() string {
+	if r.Passed() {
+		return fmt.Sprintf("%s: ok", r.Scenario)
+	}
+	return fmt.Sprintf("%s:\n  %s", r.Scenario, strings.Join(r.Diffs, "\n  "))
+}
+
+// T is the part of a *testing.T that Check reports to, so that the package
+// doesn't depend on testing outside of tests.
+type T interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// Check runs s and fails t with every diff.
+func Check(t T, s *Scenario) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	report := Run(ctx, s)
+	for _, diff := range report.Diffs {
+		t.Errorf("%s", diff)
+	}
+}
+
+// Run executes s against a fresh LiftService on a virtual clock.
+func Run(ctx context.Context, s *Scenario) Report {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	v := clock.NewVirtual(epoch)
+	rec := &recorder{PubSub: pubsub.NewMemoryPubSub()}
+	r := &run{
+		ctx:      ctx,
+		svc:      lift.NewLiftService(ctx, rec, lift.WithClock(v)),
+		recorder: rec,
+		lifts:    make(map[string]lift.LiftId),
+	}
+	report := Report{Scenario: s.name}
+
+	actions := make([]action, len(s.actions))
+	copy(actions, s.actions)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].at < actions[j].at
+	})
+	for _, a := range actions {
+		if !v.RunUntil(ctx, epoch.Add(a.at), settle) {
+			report.Diffs = append(report.Diffs, fmt.Sprintf("timed out before %s", a.at))
+			return report
+		}
+		if err := a.run(r); err != nil {
+			report.Diffs = append(report.Diffs, fmt.Sprintf("at %s, %s: %v", a.at, a.desc, err))
+		}
+	}
+	if !v.RunUntil(ctx, epoch.Add(s.end()), settle) {
+		report.Diffs = append(report.Diffs, fmt.Sprintf("timed out before %s", s.end()))
+		return report
+	}
+	rec.settle(ctx)
+
+	for _, check := range s.checks {
+		report.Diffs = append(report.Diffs, check(r)...)
+	}
+	return report
+}
+
+type run struct {
+	ctx      context.Context
+	svc      *lift.LiftService
+	recorder *recorder
+	lifts    map[string]lift.LiftId
+}
+
+var errUnknownLift = errors.New("no lift with that name")
+
+func (r *run) liftId(name string) (lift.LiftId, error) {
+	id, ok := r.lifts[name]
+	if !ok {
+		return lift.LiftId{}, fmt.Errorf("%w: %s", errUnknownLift, name)
+	}
+	return id, nil
+}
+
+func (r *run) events(id lift.LiftId) []lift.LiftEvent {
+	var events []lift.LiftEvent
+	for _, ev := range r.recorder.recorded() {
+		if ev.LiftId == id {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func (r *run) elapsed(ev lift.LiftEvent) time.Duration {
+	return ev.OccurredAt.Sub(epoch)
+}
+
+// recorder keeps every lift event as it is published. Each lift publishes
+// from a single goroutine, so a lift's events are recorded in order.
+type recorder struct {
+	pubsub.PubSub
+	events []lift.LiftEvent
+	mx     sync.Mutex
+}
+
+func (rec *recorder) Publish(topic pubsub.Topic, message pubsub.Message) error {
+	if ev, ok := message.(lift.LiftEvent); ok {
+		rec.mx.Lock()
+		rec.events = append(rec.events, ev)
+		rec.mx.Unlock()
+	}
+	return rec.PubSub.Publish(topic, message)
+}
+
+// settle waits for events still being handed over by the lifts to be recorded.
+func (rec *recorder) settle(ctx context.Context) {
+	count := -1
+	for count != len(rec.recorded()) {
+		count = len(rec.recorded())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(settle):
+		}
+	}
+}
+
+func (rec *recorder) recorded() []lift.LiftEvent {
+	rec.mx.Lock()
+	defer rec.mx.Unlock()
+	events := make([]lift.LiftEvent, len(rec.events))
+	copy(events, rec.events)
+	return events
+}
+
+func diffEvents(name string, want []Event, got []lift.LiftEvent, elapsed func(lift.LiftEvent) time.Duration) []string {
+	var diffs []string
+	for i := 0; i < max(len(want), len(got)); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, fmt.Sprintf("lift %s event %d: expected %s, got nothing", name, i, formatEvent(want[i])))
+		case i >= len(want):
+			diffs = append(diffs, fmt.Sprintf("lift %s event %d: unexpected %s at %s", name, i, formatEvent(Event{got[i].EventType, got[i].Data}), elapsed(got[i])))
+		case !matches(want[i], got[i]):
+			diffs = append(diffs, fmt.Sprintf("lift %s event %d: expected %s, got %s at %s", name, i, formatEvent(want[i]), formatEvent(Event{got[i].EventType, got[i].Data}), elapsed(got[i])))
+		}
+	}
+	return diffs
+}
+
+func matches(want Event, got lift.LiftEvent) bool {
+	if want.Type != got.EventType {
+		return false
+	}
+	return want.Data == nil || reflect.DeepEqual(want.Data, got.Data)
+}
+
+func formatEvent(ev Event) string {
+	if ev.Data == nil {
+		return ev.Type
+	}
+	return fmt.Sprintf("%s %+v", ev.Type, ev.Data)
+}
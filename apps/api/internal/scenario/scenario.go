@@ -0,0 +1,170 @@
+// Package scenario describes lift behaviour as data: a timeline of actions
+// against a LiftService and the outcomes expected from them.
+//
+//	scenario.New("lift descends after being called").
+//		At(0).AddLift("a", lift.LiftConfig{Floor: 10, FloorDelayMs: 1000}).
+//		At(2*time.Second).Call("a", 7).
+//		ExpectArrived("a", 7, 10*time.Second).
+//		ExpectEvents("a",
+//			scenario.Event{Type: "lift_added", Data: lift.LiftAdded{Floor: 10}},
+//			...)
+package scenario
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// Event is an event a lift is expected to publish. A nil Data matches any
+// data, which is useful for events carrying generated ids.
+type Event struct {
+	Type string
+	Data any
+}
+
+type Scenario struct {
+	name    string
+	at      time.Duration
+	until   time.Duration // latest time an expectation refers to
+	actions []action
+	checks  []check
+}
+
+// action runs at a point in simulated time. Any error is reported as a diff.
+type action struct {
+	at   time.Duration
+	desc string
+	run  func(r *run) error
+}
+
+// check is evaluated once the scenario has finished running.
+type check func(r *run) []string
+
+func New(name string) *Scenario {
+	return &Scenario{name: name}
+}
+
+func (s *Scenario) Name() string {
+	return s.name
+}
+
+// At sets the simulated time, measured from the start of the scenario, at
+// which the actions that follow take place.
+func (s *Scenario) At(t time.Duration) *Scenario {
+	s.at = t
+	return s
+}
+
+// AddLift adds a lift that later steps refer to by name.
+func (s *Scenario) AddLift(name string, cfg lift.LiftConfig) *Scenario {
+	return s.do("add lift "+name, func(r *run) error {
+		l, err := r.svc.AddLift(r.ctx, cfg)
+		if err != nil {
+			return err
+		}
+		r.lifts[name] = l.Id
+		return nil
+	})
+}
+
+// Call makes a hall call to the named lift.
+func (s *Scenario) Call(name string, floor int) *Scenario {
+	return s.do(fmt.Sprintf("call %s to %d", name, floor), func(r *run) error {
+		id, err := r.liftId(name)
+		if err != nil {
+			return err
+		}
+		_, err = r.svc.CallLift(r.ctx, id, floor)
+		return err
+	})
+}
+
+// CarCall presses the button for floor inside the named lift.
+func (s *Scenario) CarCall(name string, floor int) *Scenario {
+	return s.do(fmt.Sprintf("car call %s to %d", name, floor), func(r *run) error {
+		id, err := r.liftId(name)
+		if err != nil {
+			return err
+		}
+		_, err = r.svc.CarCall(r.ctx, id, floor)
+		return err
+	})
+}
+
+// Do runs an arbitrary step against the service, such as starting an
+// emergency recall.
+func (s *Scenario) Do(desc string, fn func(svc *lift.LiftService) error) *Scenario {
+	return s.do(desc, func(r *run) error {
+		return fn(r.svc)
+	})
+}
+
+// ExpectFloor expects the named lift to be at floor at the current time.
+func (s *Scenario) ExpectFloor(name string, floor int) *Scenario {
+	return s.do(fmt.Sprintf("expect %s at floor %d", name, floor), func(r *run) error {
+		id, err := r.liftId(name)
+		if err != nil {
+			return err
+		}
+		l, err := r.svc.GetLift(r.ctx, id)
+		if err != nil {
+			return err
+		}
+		if l.Floor != floor {
+			return fmt.Errorf("lift %s is at floor %d", name, l.Floor)
+		}
+		return nil
+	})
+}
+
+// ExpectArrived expects the named lift to have arrived at floor no later than by.
+func (s *Scenario) ExpectArrived(name string, floor int, by time.Duration) *Scenario {
+	s.until = max(s.until, by)
+	s.checks = append(s.checks, func(r *run) []string {
+		id, ok := r.lifts[name]
+		if !ok {
+			return []string{fmt.Sprintf("expected lift %s to arrive at %d, but it was never added", name, floor)}
+		}
+		for _, ev := range r.events(id) {
+			arrived, ok := ev.Data.(lift.LiftArrived)
+			if ev.EventType != "lift_arrived" || !ok || arrived.Floor != floor {
+				continue
+			}
+			if at := r.elapsed(ev); at > by {
+				return []string{fmt.Sprintf("expected lift %s to arrive at %d by %s, arrived at %s", name, floor, by, at)}
+			}
+			return nil
+		}
+		return []string{fmt.Sprintf("expected lift %s to arrive at %d by %s, it never arrived", name, floor, by)}
+	})
+	return s
+}
+
+// ExpectEvents expects the named lift to publish exactly events, in order,
+// over the whole scenario.
+func (s *Scenario) ExpectEvents(name string, events ...Event) *Scenario {
+	s.checks = append(s.checks, func(r *run) []string {
+		id, ok := r.lifts[name]
+		if !ok {
+			return []string{fmt.Sprintf("expected events from lift %s, but it was never added", name)}
+		}
+		return diffEvents(name, events, r.events(id), r.elapsed)
+	})
+	return s
+}
+
+func (s *Scenario) do(desc string, fn func(r *run) error) *Scenario {
+	s.actions = append(s.actions, action{at: s.at, desc: desc, run: fn})
+	return s
+}
+
+// end is the latest time the scenario needs to run until.
+func (s *Scenario) end() time.Duration {
+	end := s.until
+	for _, a := range s.actions {
+		end = max(end, a.at)
+	}
+	return end
+}
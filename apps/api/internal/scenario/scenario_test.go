@@ -0,0 +1,76 @@
+package scenario
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+func TestScenario(t *testing.T) {
+	t.Run("lift descends between floors after being called", func(t *testing.T) {
+		Check(t, New("descend").
+			At(0).AddLift("a", lift.LiftConfig{Floor: 10, FloorDelayMs: 1000}).
+			At(2*time.Second).Call("a", 7).
+			At(3500*time.Millisecond).ExpectFloor("a", 8).
+			ExpectArrived("a", 7, 5*time.Second).
+			ExpectEvents("a",
+				Event{Type: "lift_added", Data: lift.LiftAdded{Floor: 10}},
+				Event{Type: "lift_transited", Data: lift.LiftTransited{From: 10, To: 9}},
+				Event{Type: "lift_transited", Data: lift.LiftTransited{From: 9, To: 8}},
+				Event{Type: "lift_transited", Data: lift.LiftTransited{From: 8, To: 7}},
+				Event{Type: "lift_arrived", Data: lift.LiftArrived{Floor: 7}},
+				Event{Type: "lift_energy", Data: lift.LiftEnergy{Floor: 7, TripWh: 30, TotalWh: 30}},
+				Event{Type: "lift_call_served"},
+			))
+	})
+
+	t.Run("scan stops on the way to a further floor", func(t *testing.T) {
+		Check(t, New("scan").
+			At(0).AddLift("a", lift.LiftConfig{Floor: 0, FloorDelayMs: 1000, Scheduler: lift.SchedulerScan}).
+			Call("a", 6).
+			At(time.Second).Call("a", 3).
+			ExpectArrived("a", 3, 3*time.Second).
+			ExpectArrived("a", 6, 6*time.Second))
+	})
+
+	t.Run("lifts dwell at each stop before moving on", func(t *testing.T) {
+		Check(t, New("dwell").
+			At(0).AddLift("a", lift.LiftConfig{Floor: 0, FloorDelayMs: 1000, DoorDwellMs: 2000}).
+			Call("a", 1).
+			Call("a", 2).
+			At(2900*time.Millisecond).ExpectFloor("a", 1).
+			ExpectArrived("a", 2, 4*time.Second))
+	})
+
+	t.Run("differences from the expected behaviour are reported", func(t *testing.T) {
+		report := Run(context.Background(), New("too fast").
+			At(0).AddLift("a", lift.LiftConfig{Floor: 0, FloorDelayMs: 1000}).
+			Call("a", 5).
+			Call("b", 2).
+			ExpectArrived("a", 5, 3*time.Second).
+			ExpectEvents("a",
+				Event{Type: "lift_added", Data: lift.LiftAdded{Floor: 1}},
+			))
+
+		if report.Passed() {
+			t.Fatal("expected the scenario to fail")
+		}
+		want := []string{
+			"at 0s, call b to 2: no lift with that name: b",
+			"expected lift a to arrive at 5 by 3s, it never arrived",
+			"lift a event 0: expected lift_added {Floor:1}, got lift_added {Floor:0} at 0s",
+			"lift a event 1: unexpected lift_transited {From:0 To:1} at 0s",
+		}
+		for i, diff := range want {
+			if i >= len(report.Diffs) || report.Diffs[i] != diff {
+				t.Fatalf("expected diffs to start with %q, got %s", want, report)
+			}
+		}
+		if !strings.HasPrefix(report.String(), "too fast:\n") {
+			t.Errorf("expected the report to name the scenario, got %s", report)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+const defaultSettle = 500 * time.Microsecond
+
+var ErrInvalidBenchmark = errors.New("invalid benchmark config")
+
+type BenchmarkConfig struct {
+	Traffic    TrafficConfig
+	Lifts      int
+	FloorDelay time.Duration
+	// Duration is how long passengers keep arriving, in simulated time.
+	Duration time.Duration
+	// Energy prices the lifts' movement. Defaults to lift.DefaultEnergyModel.
+	Energy *lift.EnergyModel
+	// Parking decides where idle lifts wait. Its lobby and floors are taken
+	// from the traffic's building.
+	Parking lift.ParkingConfig
+	// Settle is how long, in real time, the virtual clock waits for the lifts
+	// to go quiet before jumping to the next event. Defaults to 500µs. A lift
+	// slower than that to react is left behind, so results vary between runs.
+	Settle time.Duration
+}
+
+// Result summarises one scheduler's run of a benchmark.
+type Result struct {
+	Scheduler       lift.Scheduler
+	Passengers      int
+	Failed          int
+	AverageWait     time.Duration
+	AverageJourney  time.Duration
+	FloorsTravelled int
+	Stops           int
+	EnergyWh        float64
+	// Elapsed is the simulated time taken for every passenger to arrive.
+	Elapsed time.Duration
+}
+
+// Benchmark runs the configured traffic through a fresh building whose lifts
+// all use scheduler, on a virtual clock. Lifts start at the lobby. The same
+// traffic config brings the same passengers, but results can differ slightly
+// between runs, as described for Settle.
+func Benchmark(ctx context.Context, cfg BenchmarkConfig, scheduler lift.Scheduler) (Result, error) {
+	if cfg.Lifts < 1 || cfg.FloorDelay < 0 || cfg.Duration <= 0 {
+		return Result{}, ErrInvalidBenchmark
+	}
+	gen, err := NewGenerator(cfg.Traffic)
+	if err != nil {
+		return Result{}, err
+	}
+	settle := cfg.Settle
+	if settle <= 0 {
+		settle = defaultSettle
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := clock.NewVirtual(start)
+	opts := []lift.Option{lift.WithClock(v)}
+	if cfg.Energy != nil {
+		opts = append(opts, lift.WithEnergyModel(*cfg.Energy))
+	}
+	parking := cfg.Parking
+	parking.Lobby, parking.Floors = cfg.Traffic.Building.Lobby, cfg.Traffic.Building.Floors
+	opts = append(opts, lift.WithParking(parking))
+	svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub(), opts...)
+	for i := 0; i < cfg.Lifts; i++ {
+		_, err := svc.AddLift(ctx, lift.LiftConfig{
+			Floor:        cfg.Traffic.Building.Lobby,
+			FloorDelayMs: int(cfg.FloorDelay.Milliseconds()),
+			Scheduler:    scheduler,
+		})
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	go v.Drive(ctx, settle)
+	trips := NewRunner(svc, gen, v).Run(ctx, cfg.Duration)
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	elapsed := v.Now().Sub(start)
+	lifts, err := svc.GetLifts(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := summarise(trips, lifts)
+	result.Scheduler = scheduler
+	result.Elapsed = elapsed
+	return result, nil
+}
+
+func summarise(trips []Trip, lifts []lift.Lift) Result {
+	result := Result{Passengers: len(trips)}
+	var wait, journey time.Duration
+	for _, trip := range trips {
+		if trip.Err != nil {
+			result.Failed++
+			continue
+		}
+		wait += trip.Wait
+		journey += trip.Journey
+	}
+	if completed := len(trips) - result.Failed; completed > 0 {
+		result.AverageWait = wait / time.Duration(completed)
+		result.AverageJourney = journey / time.Duration(completed)
+	}
+	for _, l := range lifts {
+		result.FloorsTravelled += l.FloorsTravelled
+		result.Stops += l.Stops
+		result.EnergyWh += l.EnergyWh
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+func TestBenchmark(t *testing.T) {
+	cfg := BenchmarkConfig{
+		Traffic: TrafficConfig{
+			Building:          Building{Floors: 10},
+			Pattern:           PatternUpPeak,
+			ArrivalsPerMinute: 6,
+			Seed:              1,
+		},
+		Lifts:      2,
+		FloorDelay: 2 * time.Second,
+		Duration:   10 * time.Minute,
+	}
+
+	t.Run("runs traffic on a virtual clock", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		started := time.Now()
+
+		result, err := Benchmark(ctx, cfg, lift.SchedulerScan)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if result.Passengers == 0 || result.Failed != 0 {
+			t.Fatalf("expected every passenger to arrive, got %+v", result)
+		}
+		if result.Elapsed < cfg.Duration-time.Minute {
+			t.Errorf("expected around %s of simulated time, got %s", cfg.Duration, result.Elapsed)
+		}
+		if time.Since(started) > result.Elapsed {
+			t.Errorf("expected the simulation to run faster than real time")
+		}
+		if result.AverageWait <= 0 || result.AverageJourney <= 0 {
+			t.Errorf("expected wait and journey times, got %+v", result)
+		}
+		if result.FloorsTravelled == 0 || result.EnergyWh == 0 {
+			t.Errorf("expected the lifts to use energy travelling, got %+v", result)
+		}
+	})
+
+	t.Run("invalid configs are rejected", func(t *testing.T) {
+		invalid := cfg
+		invalid.Lifts = 0
+		if _, err := Benchmark(context.Background(), invalid, lift.SchedulerFIFO); !errors.Is(err, ErrInvalidBenchmark) {
+			t.Errorf("expected invalid benchmark error, got %v", err)
+		}
+		if _, err := Benchmark(context.Background(), cfg, "lifo"); !errors.Is(err, lift.ErrUnknownScheduler) {
+			t.Errorf("expected unknown scheduler error, got %v", err)
+		}
+	})
+}
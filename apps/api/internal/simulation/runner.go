@@ -0,0 +1,103 @@
+package simulation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+)
+
+// Trip is the outcome of a single passenger's journey.
+type Trip struct {
+	Passenger
+	LiftId lift.LiftId // the first lift boarded
+	// Wait is the time from the hall call to the lift arriving at the origin.
+	Wait time.Duration
+	// Journey is the time from boarding to arriving at the destination,
+	// including any waits to change lifts.
+	Journey time.Duration
+	Err     error
+}
+
+// Runner feeds passengers from a Generator into a LiftService. Each passenger
+// makes a hall call at their origin and, once the lift arrives, a car call to
+// their destination, changing lifts on the way if no lift serves both.
+type Runner struct {
+	svc   *lift.LiftService
+	gen   *Generator
+	clock clock.Clock
+}
+
+func NewRunner(svc *lift.LiftService, gen *Generator, c clock.Clock) *Runner {
+	return &Runner{svc: svc, gen: gen, clock: c}
+}
+
+// Run generates passengers for duration and returns their trips once every
+// passenger has arrived, failed, or ctx is done.
+func (r *Runner) Run(ctx context.Context, duration time.Duration) []Trip {
+	start := r.clock.Now()
+	passengers := r.gen.Until(duration)
+	trips := make([]Trip, len(passengers))
+	wg := sync.WaitGroup{}
+
+	for i, p := range passengers {
+		if wait := p.At - r.clock.Now().Sub(start); wait > 0 {
+			select {
+			case <-ctx.Done():
+				trips = trips[:i]
+				wg.Wait()
+				return trips
+			case <-r.clock.After(wait):
+			}
+		}
+		wg.Add(1)
+		go func(i int, p Passenger) {
+			defer wg.Done()
+			trips[i] = r.travel(ctx, p)
+		}(i, p)
+	}
+
+	wg.Wait()
+	return trips
+}
+
+func (r *Runner) travel(ctx context.Context, p Passenger) Trip {
+	trip := Trip{Passenger: p}
+	var boarded time.Time
+	floor := p.Origin
+	for floor != p.Destination {
+		hallCall, legs, err := r.svc.DispatchTo(ctx, floor, p.Destination)
+		if err != nil {
+			trip.Err = err
+			return trip
+		}
+		pickup, err := hallCall.Wait(ctx)
+		if floor == p.Origin {
+			trip.LiftId = pickup.LiftId
+			trip.Wait = pickup.WaitTime()
+		}
+		if err != nil {
+			trip.Err = err
+			return trip
+		}
+
+		carCall, err := r.svc.CarCall(ctx, pickup.LiftId, legs[0].To)
+		if err != nil {
+			trip.Err = err
+			return trip
+		}
+		if boarded.IsZero() {
+			boarded = carCall.Call().RequestedAt
+		}
+		dropOff, err := carCall.Wait(ctx)
+		if err != nil {
+			trip.Err = err
+			return trip
+		}
+		trip.Journey = dropOff.ArrivedAt.Sub(boarded)
+		floor = legs[0].To
+	}
+	return trip
+}
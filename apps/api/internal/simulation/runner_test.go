@@ -0,0 +1,40 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/leow93/miffed-api/internal/clock"
+	"github.com/leow93/miffed-api/internal/lift"
+	"github.com/leow93/miffed-api/internal/pubsub"
+)
+
+func TestRunner(t *testing.T) {
+	t.Run("passengers are carried to their destinations", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		svc := lift.NewLiftService(ctx, pubsub.NewMemoryPubSub())
+		svc.AddLift(ctx, lift.LiftConfig{Floor: 0, FloorDelayMs: 1})
+		svc.AddLift(ctx, lift.LiftConfig{Floor: 5, FloorDelayMs: 1})
+		gen := newTestGenerator(t, TrafficConfig{
+			Building:          Building{Floors: 6},
+			Pattern:           PatternLunch,
+			ArrivalsPerMinute: 1200,
+			Seed:              11,
+		})
+
+		trips := NewRunner(svc, gen, clock.Real).Run(ctx, 200*time.Millisecond)
+		if len(trips) == 0 {
+			t.Fatal("expected some trips")
+		}
+		for _, trip := range trips {
+			if trip.Err != nil {
+				t.Fatalf("expected no error, got %v for %+v", trip.Err, trip)
+			}
+			if trip.Journey <= 0 {
+				t.Errorf("expected a journey time, got %+v", trip)
+			}
+		}
+	})
+}
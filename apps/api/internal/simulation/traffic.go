@@ -0,0 +1,198 @@
+package simulation
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Pattern describes where passengers travel from and to.
+type Pattern string
+
+const (
+	// PatternUpPeak is the morning rush: most passengers travel up from the lobby.
+	PatternUpPeak Pattern = "up_peak"
+	// PatternDownPeak is the evening rush: most passengers travel down to the lobby.
+	PatternDownPeak Pattern = "down_peak"
+	// PatternLunch is two-way traffic to and from the lobby with some inter-floor trips.
+	PatternLunch Pattern = "lunch"
+	// PatternInterFloor is traffic between floors other than the lobby.
+	PatternInterFloor Pattern = "inter_floor"
+	// PatternUniform picks origin and destination from every floor, lobby included.
+	PatternUniform Pattern = "uniform"
+)
+
+// mix is the proportion of passengers arriving at the lobby (incoming),
+// leaving via the lobby (outgoing) and travelling between other floors.
+type mix struct {
+	incoming   float64
+	outgoing   float64
+	interFloor float64
+}
+
+var mixes = map[Pattern]mix{
+	PatternUpPeak:     {incoming: 0.85, outgoing: 0.05, interFloor: 0.10},
+	PatternDownPeak:   {incoming: 0.05, outgoing: 0.85, interFloor: 0.10},
+	PatternLunch:      {incoming: 0.45, outgoing: 0.45, interFloor: 0.10},
+	PatternInterFloor: {interFloor: 1},
+}
+
+var (
+	ErrUnknownPattern = errors.New("unknown traffic pattern")
+	ErrInvalidTraffic = errors.New("invalid traffic config")
+)
+
+type Building struct {
+	// Floors is the number of floors, numbered from 0.
+	Floors int
+	Lobby  int
+	// Population weights how likely each floor is to be chosen as an origin
+	// or destination. Defaults to an equal weight for every floor.
+	Population []float64
+}
+
+type TrafficConfig struct {
+	Building Building
+	Pattern  Pattern
+	// ArrivalsPerMinute is the mean rate of the Poisson arrival process.
+	ArrivalsPerMinute float64
+	Seed              int64
+}
+
+// Passenger arrives at Origin after At has elapsed and wants to go to Destination.
+type Passenger struct {
+	At          time.Duration
+	Origin      int
+	Destination int
+}
+
+// Generator produces a reproducible stream of passengers for a traffic config.
+type Generator struct {
+	cfg        TrafficConfig
+	population []float64
+	rng        *rand.Rand
+	elapsed    time.Duration
+}
+
+func NewGenerator(cfg TrafficConfig) (*Generator, error) {
+	b := cfg.Building
+	if b.Floors < 2 {
+		return nil, fmt.Errorf("%w: a building needs at least 2 floors", ErrInvalidTraffic)
+	}
+	if b.Lobby < 0 || b.Lobby >= b.Floors {
+		return nil, fmt.Errorf("%w: lobby %d is not in the building", ErrInvalidTraffic, b.Lobby)
+	}
+	if cfg.ArrivalsPerMinute <= 0 {
+		return nil, fmt.Errorf("%w: arrivals per minute must be positive", ErrInvalidTraffic)
+	}
+	if _, ok := mixes[cfg.Pattern]; !ok && cfg.Pattern != PatternUniform {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPattern, cfg.Pattern)
+	}
+
+	population := b.Population
+	if population == nil {
+		population = make([]float64, b.Floors)
+		for i := range population {
+			population[i] = 1
+		}
+	}
+	if len(population) != b.Floors {
+		return nil, fmt.Errorf("%w: population has %d floors, want %d", ErrInvalidTraffic, len(population), b.Floors)
+	}
+	upper := 0.0
+	for floor, weight := range population {
+		if weight < 0 {
+			return nil, fmt.Errorf("%w: negative population on floor %d", ErrInvalidTraffic, floor)
+		}
+		if floor != b.Lobby {
+			upper += weight
+		}
+	}
+	if upper == 0 {
+		return nil, fmt.Errorf("%w: no population above the lobby", ErrInvalidTraffic)
+	}
+
+	return &Generator{
+		cfg:        cfg,
+		population: population,
+		rng:        rand.New(rand.NewSource(cfg.Seed)),
+	}, nil
+}
+
+// Next returns the next passenger. Inter-arrival times are exponentially
+// distributed, so arrivals form a Poisson process.
+func (g *Generator) Next() Passenger {
+	meanGap := float64(time.Minute) / g.cfg.ArrivalsPerMinute
+	g.elapsed += time.Duration(g.rng.ExpFloat64() * meanGap)
+	origin, destination := g.journey()
+	return Passenger{At: g.elapsed, Origin: origin, Destination: destination}
+}
+
+// Until returns every passenger arriving within d of the generator's start.
+func (g *Generator) Until(d time.Duration) []Passenger {
+	var passengers []Passenger
+	for {
+		p := g.Next()
+		if p.At > d {
+			return passengers
+		}
+		passengers = append(passengers, p)
+	}
+}
+
+func (g *Generator) journey() (int, int) {
+	lobby := g.cfg.Building.Lobby
+	if g.cfg.Pattern == PatternUniform {
+		origin := g.pickFloor(-1, -1)
+		destination := g.pickFloor(origin, -1)
+		if destination < 0 {
+			destination = lobby
+		}
+		return origin, destination
+	}
+
+	m := mixes[g.cfg.Pattern]
+	r := g.rng.Float64() * (m.incoming + m.outgoing + m.interFloor)
+	switch {
+	case r < m.incoming:
+		return lobby, g.pickFloor(lobby, -1)
+	case r < m.incoming+m.outgoing:
+		return g.pickFloor(lobby, -1), lobby
+	default:
+		origin := g.pickFloor(lobby, -1)
+		destination := g.pickFloor(lobby, origin)
+		if destination < 0 {
+			// only one populated floor above the lobby
+			destination = lobby
+		}
+		return origin, destination
+	}
+}
+
+// pickFloor chooses a floor weighted by population, never returning either of
+// the excluded floors. It returns -1 if there is no floor to choose from.
+func (g *Generator) pickFloor(excludeA, excludeB int) int {
+	total := 0.0
+	for floor, weight := range g.population {
+		if floor != excludeA && floor != excludeB {
+			total += weight
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+	r := g.rng.Float64() * total
+	last := -1
+	for floor, weight := range g.population {
+		if floor == excludeA || floor == excludeB || weight == 0 {
+			continue
+		}
+		last = floor
+		if r < weight {
+			return floor
+		}
+		r -= weight
+	}
+	return last
+}
@@ -0,0 +1,134 @@
+package simulation
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestGenerator(t *testing.T, cfg TrafficConfig) *Generator {
+	t.Helper()
+	gen, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return gen
+}
+
+func TestGenerator(t *testing.T) {
+	building := Building{Floors: 10}
+
+	t.Run("the same seed produces the same passengers", func(t *testing.T) {
+		cfg := TrafficConfig{Building: building, Pattern: PatternLunch, ArrivalsPerMinute: 30, Seed: 42}
+		a := newTestGenerator(t, cfg).Until(10 * time.Minute)
+		b := newTestGenerator(t, cfg).Until(10 * time.Minute)
+		if !reflect.DeepEqual(a, b) {
+			t.Error("expected identical passengers for the same seed")
+		}
+
+		cfg.Seed = 43
+		c := newTestGenerator(t, cfg).Until(10 * time.Minute)
+		if reflect.DeepEqual(a, c) {
+			t.Error("expected different passengers for a different seed")
+		}
+	})
+
+	t.Run("arrivals follow the configured rate", func(t *testing.T) {
+		gen := newTestGenerator(t, TrafficConfig{Building: building, Pattern: PatternUniform, ArrivalsPerMinute: 20, Seed: 1})
+		passengers := gen.Until(time.Hour)
+		if math.Abs(float64(len(passengers))-1200) > 120 {
+			t.Errorf("expected roughly 1200 passengers, got %d", len(passengers))
+		}
+		for i := 1; i < len(passengers); i++ {
+			if passengers[i].At < passengers[i-1].At {
+				t.Fatalf("expected passengers in arrival order, got %s after %s", passengers[i].At, passengers[i-1].At)
+			}
+		}
+	})
+
+	t.Run("passengers never travel to the floor they are on", func(t *testing.T) {
+		for _, pattern := range []Pattern{PatternUpPeak, PatternDownPeak, PatternLunch, PatternInterFloor, PatternUniform} {
+			gen := newTestGenerator(t, TrafficConfig{Building: building, Pattern: pattern, ArrivalsPerMinute: 60, Seed: 7})
+			for _, p := range gen.Until(time.Hour) {
+				if p.Origin == p.Destination {
+					t.Fatalf("%s: passenger travelling from %d to itself", pattern, p.Origin)
+				}
+				if p.Origin < 0 || p.Origin >= 10 || p.Destination < 0 || p.Destination >= 10 {
+					t.Fatalf("%s: passenger outside the building %+v", pattern, p)
+				}
+			}
+		}
+	})
+
+	t.Run("up peak traffic mostly leaves the lobby", func(t *testing.T) {
+		gen := newTestGenerator(t, TrafficConfig{Building: building, Pattern: PatternUpPeak, ArrivalsPerMinute: 60, Seed: 3})
+		passengers := gen.Until(time.Hour)
+		fromLobby := 0
+		for _, p := range passengers {
+			if p.Origin == 0 {
+				fromLobby++
+			}
+		}
+		if share := float64(fromLobby) / float64(len(passengers)); share < 0.8 || share > 0.9 {
+			t.Errorf("expected around 85%% of trips from the lobby, got %.2f", share)
+		}
+	})
+
+	t.Run("down peak traffic mostly heads to the lobby", func(t *testing.T) {
+		gen := newTestGenerator(t, TrafficConfig{Building: building, Pattern: PatternDownPeak, ArrivalsPerMinute: 60, Seed: 3})
+		passengers := gen.Until(time.Hour)
+		toLobby := 0
+		for _, p := range passengers {
+			if p.Destination == 0 {
+				toLobby++
+			}
+		}
+		if share := float64(toLobby) / float64(len(passengers)); share < 0.8 || share > 0.9 {
+			t.Errorf("expected around 85%% of trips to the lobby, got %.2f", share)
+		}
+	})
+
+	t.Run("inter floor traffic avoids the lobby", func(t *testing.T) {
+		gen := newTestGenerator(t, TrafficConfig{Building: building, Pattern: PatternInterFloor, ArrivalsPerMinute: 60, Seed: 5})
+		for _, p := range gen.Until(time.Hour) {
+			if p.Origin == 0 || p.Destination == 0 {
+				t.Fatalf("expected no lobby trips, got %+v", p)
+			}
+		}
+	})
+
+	t.Run("population weights decide which floors are used", func(t *testing.T) {
+		population := []float64{1, 0, 0, 5, 0}
+		gen := newTestGenerator(t, TrafficConfig{
+			Building:          Building{Floors: 5, Population: population},
+			Pattern:           PatternUpPeak,
+			ArrivalsPerMinute: 60,
+			Seed:              9,
+		})
+		for _, p := range gen.Until(time.Hour) {
+			if p.Origin != 0 && p.Origin != 3 || p.Destination != 0 && p.Destination != 3 {
+				t.Fatalf("expected trips between floors 0 and 3, got %+v", p)
+			}
+		}
+	})
+
+	t.Run("invalid configs are rejected", func(t *testing.T) {
+		cases := map[string]TrafficConfig{
+			"too few floors":    {Building: Building{Floors: 1}, Pattern: PatternUpPeak, ArrivalsPerMinute: 1},
+			"lobby outside":     {Building: Building{Floors: 5, Lobby: 5}, Pattern: PatternUpPeak, ArrivalsPerMinute: 1},
+			"no arrivals":       {Building: building, Pattern: PatternUpPeak},
+			"population length": {Building: Building{Floors: 3, Population: []float64{1}}, Pattern: PatternUpPeak, ArrivalsPerMinute: 1},
+			"empty building":    {Building: Building{Floors: 3, Population: []float64{1, 0, 0}}, Pattern: PatternUpPeak, ArrivalsPerMinute: 1},
+		}
+		for name, cfg := range cases {
+			if _, err := NewGenerator(cfg); !errors.Is(err, ErrInvalidTraffic) {
+				t.Errorf("%s: expected invalid traffic error, got %v", name, err)
+			}
+		}
+		if _, err := NewGenerator(TrafficConfig{Building: building, Pattern: "rush", ArrivalsPerMinute: 1}); !errors.Is(err, ErrUnknownPattern) {
+			t.Errorf("expected unknown pattern error, got %v", err)
+		}
+	})
+}